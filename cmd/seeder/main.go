@@ -9,8 +9,9 @@ import (
 	"time"
 )
 
+// Orders are sent without a customer_id: it has to reference an existing
+// customer, and the generated ones are one-offs.
 type Order struct {
-	CustomerID      int      `json:"customer_id"`
 	CustomerName    string   `json:"customer_name"`
 	Lat             float64  `json:"lat"`
 	Lon             float64  `json:"lon"`
@@ -74,7 +75,6 @@ func main() {
 		}
 
 		orders = append(orders, Order{
-			CustomerID:      1000 + i,
 			CustomerName:    fmt.Sprintf("%s - %s %d", namePrefix, city.Name, i),
 			Lat:             lat,
 			Lon:             lon,
@@ -8,7 +8,9 @@ import (
 
 	"route-go/internal/api"
 	"route-go/internal/db"
+	"route-go/internal/matrix"
 	"route-go/internal/pubsub"
+	"route-go/internal/solver"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -59,13 +61,25 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "If-Match", "X-User"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	handler := &api.Router{Repo: repo, PubSub: psClient}
+	// Distance/time matrix: haversine unless an OSRM server is configured,
+	// cached in Postgres so each planning date computes it once
+	var provider matrix.Provider = matrix.Haversine{}
+	if osrmURL := os.Getenv("OSRM_URL"); osrmURL != "" {
+		provider = matrix.NewOSRM(osrmURL)
+	}
+	provider = matrix.NewCached(provider, repo)
+
+	handler := &api.Router{Repo: repo, PubSub: psClient, Matrix: provider}
+	// OPTIMIZER=go runs the native solver instead of the Python worker
+	if os.Getenv("OPTIMIZER") == "go" {
+		handler.Optimizer = &solver.Service{Repo: repo, Matrix: provider, Options: solver.DefaultOptions()}
+	}
 	handler.RegisterRoutes(r)
 
 	if err := r.Run(":8080"); err != nil {
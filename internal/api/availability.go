@@ -0,0 +1,187 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"route-go/internal/db"
+	"route-go/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetAvailability returns a vehicle's calendar. With ?date=YYYY-MM-DD it
+// also resolves the hours the vehicle works that day.
+func (r *Router) GetAvailability(c *gin.Context) {
+	id, ok := r.availabilityVehicle(c)
+	if !ok {
+		return
+	}
+	a, err := r.Repo.GetAvailability(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	dateStr := c.Query("date")
+	if dateStr == "" {
+		c.JSON(http.StatusOK, a)
+		return
+	}
+	date, err := time.Parse(db.DateLayout, dateStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+	day := gin.H{"date": dateStr, "available": false}
+	if start, end, ok := a.HoursOn(date); ok {
+		day = gin.H{"date": dateStr, "available": true, "starts_at": start, "ends_at": end}
+	}
+	c.JSON(http.StatusOK, gin.H{"vehicle_id": a.VehicleID, "shifts": a.Shifts, "exceptions": a.Exceptions, "on": day})
+}
+
+// ReplaceShifts sets the weekly working hours of a vehicle.
+func (r *Router) ReplaceShifts(c *gin.Context) {
+	id, ok := r.availabilityVehicle(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Shifts []db.Shift `json:"shifts" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	seen := make(map[int]bool, len(req.Shifts))
+	for _, s := range req.Shifts {
+		if seen[s.Weekday] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("weekday %d has more than one shift", s.Weekday)})
+			return
+		}
+		seen[s.Weekday] = true
+		if s.StartsAt >= s.EndsAt {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("shift on weekday %d must start before it ends", s.Weekday)})
+			return
+		}
+	}
+
+	if err := r.Repo.ReplaceShifts(c.Request.Context(), id, req.Shifts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.respondAvailability(c, id)
+}
+
+// SaveAvailabilityException marks a date as a day off or gives it its own hours.
+func (r *Router) SaveAvailabilityException(c *gin.Context) {
+	id, ok := r.availabilityVehicle(c)
+	if !ok {
+		return
+	}
+	date, ok := exceptionDate(c)
+	if !ok {
+		return
+	}
+
+	var e db.AvailabilityException
+	if err := c.ShouldBindJSON(&e); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	e.Date = date
+	if !e.Available && (e.StartsAt != nil || e.EndsAt != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a day off has no hours"})
+		return
+	}
+	if e.StartsAt != nil && e.EndsAt != nil && *e.StartsAt >= *e.EndsAt {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "starts_at must be before ends_at"})
+		return
+	}
+	// Hours left out come from the weekday's shift and must still fit with it
+	ctx := c.Request.Context()
+	if e.Available && (e.StartsAt == nil) != (e.EndsAt == nil) {
+		a, err := r.Repo.GetAvailability(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		day, _ := time.Parse(db.DateLayout, date)
+		resolved := db.Availability{Shifts: a.Shifts, Exceptions: []db.AvailabilityException{e}}
+		if start, end, _ := resolved.HoursOn(day); start >= end {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("with the weekday's shift the vehicle would work from %s to %s, give both starts_at and ends_at",
+				validation.FormatMinutes(start), validation.FormatMinutes(end))})
+			return
+		}
+	}
+
+	if err := r.Repo.SaveAvailabilityException(ctx, id, &e); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.respondAvailability(c, id)
+}
+
+func (r *Router) DeleteAvailabilityException(c *gin.Context) {
+	id, ok := r.availabilityVehicle(c)
+	if !ok {
+		return
+	}
+	date, ok := exceptionDate(c)
+	if !ok {
+		return
+	}
+
+	err := r.Repo.DeleteAvailabilityException(c.Request.Context(), id, date)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no exception on that date"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.respondAvailability(c, id)
+}
+
+// availabilityVehicle parses the vehicle id and checks it exists, writing the
+// error response when it does not.
+func (r *Router) availabilityVehicle(c *gin.Context) (int, bool) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	if _, err := r.Repo.GetVehicle(c.Request.Context(), id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "vehicle not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return 0, false
+	}
+	return id, true
+}
+
+func exceptionDate(c *gin.Context) (string, bool) {
+	date := c.Param("date")
+	if _, err := time.Parse(db.DateLayout, date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return "", false
+	}
+	return date, true
+}
+
+func (r *Router) respondAvailability(c *gin.Context, vehicleID int) {
+	a, err := r.Repo.GetAvailability(c.Request.Context(), vehicleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
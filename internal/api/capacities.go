@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) ListCapacityDimensions(c *gin.Context) {
+	dims, err := r.Repo.ListCapacityDimensions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dims)
+}
+
+// SaveCapacityDimension adds a dimension (e.g. volume in m3) or changes its unit.
+func (r *Router) SaveCapacityDimension(c *gin.Context) {
+	var d db.CapacityDimension
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := r.Repo.SaveCapacityDimension(c.Request.Context(), &d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// capacityDimensions loads the configured dimensions to validate quantities
+// against, writing the error response on failure.
+func (r *Router) capacityDimensions(c *gin.Context) ([]db.CapacityDimension, bool) {
+	dims, err := r.Repo.ListCapacityDimensions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return dims, true
+}
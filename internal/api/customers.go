@@ -0,0 +1,202 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// defaultSearchRadiusM bounds a proximity search without ?radius_m=.
+const defaultSearchRadiusM = 5000
+
+// bindCustomer binds the body over cust, so fields left out keep the value
+// preset by the caller, and checks its quantities against the configured
+// capacity dimensions.
+func (r *Router) bindCustomer(c *gin.Context, cust *db.Customer) bool {
+	if err := c.ShouldBindJSON(cust); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	dims, ok := r.capacityDimensions(c)
+	if !ok {
+		return false
+	}
+	if err := cust.Quantities.Validate(dims); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (r *Router) CreateCustomer(c *gin.Context) {
+	cust := db.Customer{Status: db.CustomerActive}
+	if !r.bindCustomer(c, &cust) {
+		return
+	}
+	if err := r.Repo.CreateCustomer(c.Request.Context(), &cust); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, cust)
+}
+
+// customerFilter reads ?q= (name), ?status= and the proximity search
+// ?lat=&lon=&radius_m=.
+func customerFilter(c *gin.Context) (db.CustomerFilter, error) {
+	f := db.CustomerFilter{Name: c.Query("q"), Status: c.Query("status")}
+	if f.Status != "" && f.Status != db.CustomerActive && f.Status != db.CustomerInactive {
+		return f, fmt.Errorf("invalid status, expected active or inactive")
+	}
+	lat, lon, radius := c.Query("lat"), c.Query("lon"), c.Query("radius_m")
+	if lat == "" && lon == "" {
+		if radius != "" {
+			return f, fmt.Errorf("radius_m needs lat and lon")
+		}
+		return f, nil
+	}
+	if lat == "" || lon == "" {
+		return f, fmt.Errorf("lat and lon must be given together")
+	}
+	la, err1 := strconv.ParseFloat(lat, 64)
+	lo, err2 := strconv.ParseFloat(lon, 64)
+	if err1 != nil || err2 != nil || la < -90 || la > 90 || lo < -180 || lo > 180 {
+		return f, fmt.Errorf("invalid lat or lon")
+	}
+	f.Lat, f.Lon = &la, &lo
+	f.RadiusM = defaultSearchRadiusM
+	if radius != "" {
+		rm, err := strconv.ParseFloat(radius, 64)
+		if err != nil || rm <= 0 {
+			return f, fmt.Errorf("invalid radius_m")
+		}
+		f.RadiusM = rm
+	}
+	return f, nil
+}
+
+func (r *Router) ListCustomers(c *gin.Context) {
+	f, err := customerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	customers, err := r.Repo.ListCustomers(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, customers)
+}
+
+func (r *Router) GetCustomer(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	cust, err := r.Repo.GetCustomer(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cust)
+}
+
+func (r *Router) UpdateCustomer(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	cur, err := r.Repo.GetCustomer(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Leaving status out keeps the customer as it is
+	cust := db.Customer{Status: cur.Status}
+	if !r.bindCustomer(c, &cust) {
+		return
+	}
+	cust.ID = id
+
+	err = r.Repo.UpdateCustomer(ctx, &cust)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cust)
+}
+
+func (r *Router) DeleteCustomer(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err := r.Repo.DeleteCustomer(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		c.JSON(http.StatusConflict, gin.H{"error": "customer has orders, set its status to inactive instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListCustomerOrders is the customer's order history, newest first.
+func (r *Router) ListCustomerOrders(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := r.Repo.GetCustomer(ctx, id); errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	orders, err := r.Repo.ListCustomerOrders(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
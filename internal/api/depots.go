@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (r *Router) CreateDepot(c *gin.Context) {
+	var d db.Depot
+	if err := bindDepot(c, &d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := r.Repo.CreateDepot(c.Request.Context(), &d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+func (r *Router) ListDepots(c *gin.Context) {
+	depots, err := r.Repo.ListDepots(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, depots)
+}
+
+func (r *Router) GetDepot(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	d, err := r.Repo.GetDepot(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "depot not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (r *Router) UpdateDepot(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var d db.Depot
+	if err := bindDepot(c, &d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d.ID = id
+
+	err := r.Repo.UpdateDepot(c.Request.Context(), &d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "depot not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (r *Router) DeleteDepot(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err := r.Repo.DeleteDepot(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "depot not found"})
+		return
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		c.JSON(http.StatusConflict, gin.H{"error": "depot still has vehicles, orders or routes"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// bindDepot reads a depot body. Closing time defaults to the end of the day.
+func bindDepot(c *gin.Context, d *db.Depot) error {
+	if err := c.ShouldBindJSON(d); err != nil {
+		return err
+	}
+	if d.ClosesAt == 0 {
+		d.ClosesAt = 1440
+	}
+	if d.OpensAt >= d.ClosesAt {
+		return fmt.Errorf("opens_at must be before closes_at")
+	}
+	return nil
+}
+
+// depotFilter reads the optional ?depot_id= filter; 0 means all depots.
+func depotFilter(c *gin.Context) (int, error) {
+	var depotID int
+	if s := c.Query("depot_id"); s != "" {
+		if _, err := fmt.Sscan(s, &depotID); err != nil {
+			return 0, fmt.Errorf("invalid depot_id")
+		}
+	}
+	return depotID, nil
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (r *Router) CreateDriver(c *gin.Context) {
+	var d db.Driver
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := r.Repo.CreateDriver(c.Request.Context(), &d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+func (r *Router) ListDrivers(c *gin.Context) {
+	drivers, err := r.Repo.ListDrivers(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, drivers)
+}
+
+func (r *Router) GetDriver(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	d, err := r.Repo.GetDriver(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "driver not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (r *Router) UpdateDriver(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var d db.Driver
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d.ID = id
+
+	err := r.Repo.UpdateDriver(c.Request.Context(), &d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "driver not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (r *Router) DeleteDriver(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err := r.Repo.DeleteDriver(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "driver not found"})
+		return
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		c.JSON(http.StatusConflict, gin.H{"error": "driver has assignments, set their status to inactive instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDriverAssignments lists who drives what on ?date= (today by default).
+func (r *Router) ListDriverAssignments(c *gin.Context) {
+	date := c.DefaultQuery("date", time.Now().Format(db.DateLayout))
+	if _, err := time.Parse(db.DateLayout, date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+	assignments, err := r.Repo.ListDriverAssignments(c.Request.Context(), date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, assignments)
+}
+
+// AssignDriver puts an active driver holding the vehicle's license category
+// behind its wheel for the day.
+func (r *Router) AssignDriver(c *gin.Context) {
+	var a db.DriverAssignment
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := time.Parse(db.DateLayout, a.Date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	v, err := r.Repo.GetVehicle(ctx, a.VehicleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("vehicle %d does not exist", a.VehicleID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := r.Repo.GetDriver(ctx, a.DriverID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("driver %d does not exist", a.DriverID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if d.Status != db.DriverActive {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("driver %s is %s", d.Name, d.Status)})
+		return
+	}
+	if !db.LicenseCovers(d.LicenseCategory, v.RequiredLicense) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("%s needs a category %s license, driver %s holds %s", v.Name, v.RequiredLicense, d.Name, d.LicenseCategory)})
+		return
+	}
+
+	err = r.Repo.AssignDriver(ctx, &a)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("driver %s already drives another vehicle on %s", d.Name, a.Date)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+func (r *Router) UnassignDriver(c *gin.Context) {
+	date := c.Param("date")
+	if _, err := time.Parse(db.DateLayout, date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+	var vehicleID int
+	if _, err := fmt.Sscan(c.Param("vehicle_id"), &vehicleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vehicle_id"})
+		return
+	}
+
+	err := r.Repo.UnassignDriver(c.Request.Context(), date, vehicleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "vehicle has no driver that day"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -1,45 +1,107 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"route-go/internal/db"
-
+	"route-go/internal/matrix"
 	"route-go/internal/pubsub"
+	"route-go/internal/solver"
+	"route-go/internal/validation"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 )
 
 type Router struct {
 	Repo   *db.Repository
 	PubSub *pubsub.Client
+	// Optimizer, when set, solves in-process instead of publishing to the Python worker
+	Optimizer *solver.Service
+	// Matrix is used to time routes; defaults to matrix.Haversine
+	Matrix matrix.Provider
 }
 
 func (r *Router) RegisterRoutes(g *gin.Engine) {
 	api := g.Group("/api")
 	{
+		api.POST("/depots", r.CreateDepot)
+		api.GET("/depots", r.ListDepots)
+		api.GET("/depots/:id", r.GetDepot)
+		api.PUT("/depots/:id", r.UpdateDepot)
+		api.DELETE("/depots/:id", r.DeleteDepot)
+		api.GET("/capacity-dimensions", r.ListCapacityDimensions)
+		api.POST("/capacity-dimensions", r.SaveCapacityDimension)
+		api.GET("/drop-penalties", r.ListDropPenalties)
+		api.PUT("/drop-penalties", r.SaveDropPenalties)
 		api.POST("/vehicles", r.CreateVehicle)
 		api.GET("/vehicles", r.ListVehicles)
 		api.GET("/vehicles/:id", r.GetVehicle)
 		api.PUT("/vehicles/:id", r.UpdateVehicle)
+		api.DELETE("/vehicles/:id", r.DeleteVehicle)
+		api.GET("/vehicles/:id/availability", r.GetAvailability)
+		api.PUT("/vehicles/:id/availability/shifts", r.ReplaceShifts)
+		api.PUT("/vehicles/:id/availability/exceptions/:date", r.SaveAvailabilityException)
+		api.DELETE("/vehicles/:id/availability/exceptions/:date", r.DeleteAvailabilityException)
+		api.POST("/drivers", r.CreateDriver)
+		api.GET("/drivers", r.ListDrivers)
+		api.GET("/drivers/:id", r.GetDriver)
+		api.PUT("/drivers/:id", r.UpdateDriver)
+		api.DELETE("/drivers/:id", r.DeleteDriver)
+		api.GET("/driver-assignments", r.ListDriverAssignments)
+		api.PUT("/driver-assignments", r.AssignDriver)
+		api.DELETE("/driver-assignments/:date/:vehicle_id", r.UnassignDriver)
 		api.POST("/customers", r.CreateCustomer)
 		api.GET("/customers", r.ListCustomers)
+		api.GET("/customers/:id", r.GetCustomer)
+		api.PUT("/customers/:id", r.UpdateCustomer)
+		api.DELETE("/customers/:id", r.DeleteCustomer)
+		api.GET("/customers/:id/orders", r.ListCustomerOrders)
 		api.POST("/orders", r.CreateOrder)
 		api.POST("/orders/batch", r.CreateOrderBatch)
+		api.POST("/orders/shipments", r.CreateShipment)
 		api.GET("/orders", r.ListOrders)
+		api.GET("/orders/stale", r.ListStaleOrders)
+		api.GET("/orders/:id", r.GetOrder)
+		api.PUT("/orders/:id", r.UpdateOrder)
+		api.DELETE("/orders/:id", r.DeleteOrder)
 		api.GET("/routes", r.ListRoutes)
 		api.POST("/routes", r.CreateRoute)
 		api.GET("/routes/:id", r.GetRoute)
 		api.PUT("/routes/:id", r.UpdateRoute)
 		api.POST("/routes/:id/reprocess", r.ReprocessRoute)
+		api.POST("/routes/:id/recompute", r.RecomputeRoute)
+		api.GET("/routes/:id/kpis", r.GetRouteKPIs)
+		api.POST("/routes/:id/insert", r.InsertOrders)
+		api.POST("/routes/:id/evaluate", r.EvaluateRoute)
+		api.POST("/routes/:id/confirm", r.transitionRoute(db.RouteConfirmed))
+		api.POST("/routes/:id/dispatch", r.transitionRoute(db.RouteDispatched))
+		api.POST("/routes/:id/start", r.transitionRoute(db.RouteInProgress))
+		api.POST("/routes/:id/complete", r.transitionRoute(db.RouteCompleted))
+		api.POST("/routes/:id/cancel", r.transitionRoute(db.RouteCancelled))
+		api.GET("/routes/:id/status-history", r.ListRouteStatusChanges)
+		api.GET("/routes/:id/revisions", r.ListRouteRevisions)
+		api.GET("/routes/:id/revisions/diff", r.DiffRouteRevisions)
+		api.GET("/routes/:id/revisions/:revision", r.GetRouteRevision)
+		api.POST("/routes/:id/revisions/:revision/rollback", r.RollbackRoute)
 		api.POST("/routes/optimize", r.TriggerOptimization)
+		api.GET("/optimizations/:id", r.GetOptimization)
 	}
 }
 
 func (r *Router) CreateVehicle(c *gin.Context) {
-	var v db.Vehicle
-	if err := c.ShouldBindJSON(&v); err != nil {
+	v := db.Vehicle{Active: true}
+	if err := bindVehicle(c, &v); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dims, ok := r.capacityDimensions(c)
+	if !ok {
+		return
+	}
+	if err := v.Capacities.Validate(dims); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -51,7 +113,13 @@ func (r *Router) CreateVehicle(c *gin.Context) {
 }
 
 func (r *Router) ListVehicles(c *gin.Context) {
-	vehicles, err := r.Repo.ListVehicles(c.Request.Context())
+	depotID, err := depotFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	includeInactive := c.Query("include_inactive") == "true"
+	vehicles, err := r.Repo.ListVehicles(c.Request.Context(), depotID, includeInactive)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -82,40 +150,84 @@ func (r *Router) UpdateVehicle(c *gin.Context) {
 		return
 	}
 
-	var v db.Vehicle
-	if err := c.ShouldBindJSON(&v); err != nil {
+	ctx := c.Request.Context()
+	cur, err := r.Repo.GetVehicle(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "vehicle not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Leaving active out keeps the vehicle as it is
+	v := db.Vehicle{Active: cur.Active}
+	if err := bindVehicle(c, &v); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	v.ID = id
+	dims, ok := r.capacityDimensions(c)
+	if !ok {
+		return
+	}
+	if err := v.Capacities.Validate(dims); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := r.Repo.UpdateVehicle(c.Request.Context(), &v); err != nil {
+	if err := r.Repo.UpdateVehicle(ctx, &v); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, v)
 }
 
-func (r *Router) CreateCustomer(c *gin.Context) {
-	var cust db.Customer
-	if err := c.ShouldBindJSON(&cust); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// DeleteVehicle refuses vehicles still used by a route past the draft stage,
+// which should be deactivated instead, and reports the drafts it leaves stale.
+func (r *Router) DeleteVehicle(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := r.Repo.CreateCustomer(c.Request.Context(), &cust); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+	drafts, err := r.Repo.DeleteVehicle(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "vehicle not found"})
+		return
+	}
+	if errors.Is(err, db.ErrVehicleInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
-	c.Status(http.StatusCreated)
-}
-
-func (r *Router) ListCustomers(c *gin.Context) {
-	customers, err := r.Repo.ListCustomers(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, customers)
+	c.JSON(http.StatusOK, gin.H{"affected_draft_route_ids": drafts})
+}
+
+// bindVehicle reads a vehicle body over v, so fields left out keep the value
+// preset by the caller. An end location needs both coordinates and makes no
+// sense on an open route, which ends at its last stop.
+func bindVehicle(c *gin.Context, v *db.Vehicle) error {
+	if err := c.ShouldBindJSON(v); err != nil {
+		return err
+	}
+	if (v.EndLat == nil) != (v.EndLon == nil) {
+		return fmt.Errorf("end_lat and end_lon must be given together")
+	}
+	if v.EndLat != nil {
+		if v.RouteMode == db.RouteModeOpen {
+			return fmt.Errorf("open routes cannot have an end location")
+		}
+		if *v.EndLat < -90 || *v.EndLat > 90 || *v.EndLon < -180 || *v.EndLon > 180 {
+			return fmt.Errorf("end location out of range")
+		}
+	}
+	return nil
 }
 
 func (r *Router) CreateOrder(c *gin.Context) {
@@ -124,11 +236,33 @@ func (r *Router) CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	dims, ok := r.capacityDimensions(c)
+	if !ok {
+		return
+	}
+	if err := o.Quantities.Validate(dims); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validDeliveryDate(o); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err := r.Repo.CreateOrder(c.Request.Context(), &o); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCreateOrderError(c, err)
 		return
 	}
-	c.Status(http.StatusCreated)
+	c.JSON(http.StatusCreated, o)
+}
+
+// respondCreateOrderError maps order creation failures: a customer_id that
+// does not exist or was deactivated is the client's mistake.
+func respondCreateOrderError(c *gin.Context, err error) {
+	if errors.Is(err, db.ErrCustomerNotFound) || errors.Is(err, db.ErrCustomerInactive) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
 
 func (r *Router) CreateOrderBatch(c *gin.Context) {
@@ -141,11 +275,25 @@ func (r *Router) CreateOrderBatch(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "empty batch"})
 		return
 	}
+	dims, ok := r.capacityDimensions(c)
+	if !ok {
+		return
+	}
+	for i, o := range orders {
+		if err := o.Quantities.Validate(dims); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("order %d: %v", i, err)})
+			return
+		}
+		if err := validDeliveryDate(o); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("order %d: %v", i, err)})
+			return
+		}
+	}
 
 	// Ideally execute in transaction, for now just loop
 	// repo.CreateOrdersBatch would be better
 	if err := r.Repo.CreateOrdersBatch(c.Request.Context(), orders); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCreateOrderError(c, err)
 		return
 	}
 	c.Status(http.StatusCreated)
@@ -158,7 +306,17 @@ func (r *Router) ListOrders(c *gin.Context) {
 	if routeIDStr != "" {
 		fmt.Sscan(routeIDStr, &routeID)
 	}
-	orders, err := r.Repo.ListOrders(c.Request.Context(), status, routeID)
+	depotID, err := depotFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	date, err := dateFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	orders, err := r.Repo.ListOrders(c.Request.Context(), status, routeID, depotID, date)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -166,8 +324,47 @@ func (r *Router) ListOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, orders)
 }
 
+// ListStaleOrders lists the orders created from a customer whose master data
+// has changed since.
+func (r *Router) ListStaleOrders(c *gin.Context) {
+	orders, err := r.Repo.ListStaleOrders(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// dateFilter reads the optional ?date= (YYYY-MM-DD); "" means any date.
+func dateFilter(c *gin.Context) (string, error) {
+	date := c.Query("date")
+	if date == "" {
+		return "", nil
+	}
+	if _, err := time.Parse(db.DateLayout, date); err != nil {
+		return "", fmt.Errorf("invalid date, expected YYYY-MM-DD")
+	}
+	return date, nil
+}
+
+// validDeliveryDate checks an order's optional delivery date.
+func validDeliveryDate(o db.Order) error {
+	if o.DeliveryDate == nil {
+		return nil
+	}
+	if _, err := time.Parse(db.DateLayout, *o.DeliveryDate); err != nil {
+		return fmt.Errorf("invalid delivery_date, expected YYYY-MM-DD")
+	}
+	return nil
+}
+
 func (r *Router) ListRoutes(c *gin.Context) {
-	routes, err := r.Repo.ListRoutes(c.Request.Context())
+	depotID, err := depotFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	routes, err := r.Repo.ListRoutes(c.Request.Context(), depotID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -181,11 +378,21 @@ func (r *Router) CreateRoute(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// Default status if empty
+	// Routes start as drafts and move on through the transition endpoints
 	if rt.Status == "" {
-		rt.Status = "draft"
+		rt.Status = db.RouteDraft
 	}
-	if err := r.Repo.CreateRoute(c.Request.Context(), &rt); err != nil {
+	if rt.Status != db.RouteDraft {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new routes are drafts"})
+		return
+	}
+	if rt.RouteDate != "" {
+		if _, err := time.Parse(db.DateLayout, rt.RouteDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid route_date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if err := r.Repo.CreateRoute(c.Request.Context(), &rt, changedBy(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -205,18 +412,10 @@ func (r *Router) GetRoute(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", routeETag(rt.Version))
 	c.JSON(http.StatusOK, rt)
 }
 
-// Structure to parse solution JSON for ID extraction
-type SolutionWrapper struct {
-	Vehicles []struct {
-		Route []struct {
-			OrderID int `json:"order_id"`
-		} `json:"route"`
-	} `json:"vehicles"`
-}
-
 func (r *Router) UpdateRoute(c *gin.Context) {
 	idStr := c.Param("id")
 	var id int
@@ -227,58 +426,146 @@ func (r *Router) UpdateRoute(c *gin.Context) {
 
 	// Fetch existing route to support partial updates
 	rt, err := r.Repo.GetRoute(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := c.ShouldBindJSON(rt); err != nil {
+	if !ifMatch(c, rt) {
+		return
+	}
+
+	var req struct {
+		SolutionJSON *db.Solution `json:"solution_json"`
+		Status       *string      `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	rt.ID = id
+	if req.Status != nil && *req.Status != rt.Status {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status changes go through the route transition endpoints"})
+		return
+	}
+	if req.SolutionJSON != nil && rt.Status != db.RouteDraft && rt.Status != db.RouteConfirmed {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("route is %s, only draft and confirmed routes can be edited", rt.Status)})
+		return
+	}
+
+	r.saveRouteSolution(c, rt, req.SolutionJSON, db.RevisionManual)
+}
 
-	if err := r.Repo.UpdateRoute(c.Request.Context(), rt); err != nil {
+// saveRouteSolution saves edited, or the current solution revalidated when
+// nil, as a new revision of rt from source, then re-syncs the route's orders.
+// Edited solutions are checked against live data. Warnings (capacity, time
+// windows) can be saved with ?force=true, errors never. Mandatory orders can't
+// be taken out, nor be missing from a confirmed route.
+func (r *Router) saveRouteSolution(c *gin.Context, rt *db.Route, edited *db.Solution, source string) {
+	var violations []validation.Violation
+	var plan *solver.Plan
+	var err error
+	sol := rt.SolutionJSON
+	if edited != nil {
+		sol = *edited
+		plan, violations, err = r.validateSolution(c.Request.Context(), rt, sol)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	mandatory, err := r.mandatoryViolations(c.Request.Context(), rt, sol, db.IsCommitted(rt.Status))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	violations = append(violations, mandatory...)
+	if validation.HasErrors(violations) || (len(violations) > 0 && c.Query("force") != "true") {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "solution violates route constraints", "violations": violations})
+		return
+	}
+	warnings := violations
+	if plan != nil {
+		// Stop timings from the editor are stale once stops move around
+		rt.SolutionJSON = plan.Recompute(sol)
+	}
+
+	if err := r.Repo.UpdateRoute(c.Request.Context(), rt, source, changedBy(c)); err != nil {
+		respondRouteWriteError(c, err)
+		return
+	}
 
 	// Sync Order Statuses
-	// We need to convert rt.SolutionJSON (any) to our struct to extract IDs
-	// This is a bit inefficient but robust
-	importJson, _ := json.Marshal(rt.SolutionJSON)
-	var sol SolutionWrapper
-	if err := json.Unmarshal(importJson, &sol); err == nil {
-		var orderIDs []int
-		for _, v := range sol.Vehicles {
-			for _, step := range v.Route {
-				if step.OrderID != 0 {
-					orderIDs = append(orderIDs, step.OrderID)
-				}
-			}
-		}
-		// If status is confirmed, we might want to do something else, but for now just sync orders
-		if err := r.Repo.RecruitOrdersToRoute(c.Request.Context(), rt.ID, orderIDs); err != nil {
-			// Log error but don't fail request? Or warn?
-			fmt.Printf("Error syncing orders: %v\n", err)
+	if err := r.Repo.RecruitOrdersToRoute(c.Request.Context(), rt.ID, rt.SolutionJSON.OrderIDs()); err != nil {
+		// Log error but don't fail request? Or warn?
+		fmt.Printf("Error syncing orders: %v\n", err)
+	}
+
+	// Dispatch needs to hear about changes made after confirmation
+	if db.IsCommitted(rt.Status) {
+		// Name the drivers in the response and the event
+		if err := r.Repo.AttachDrivers(c.Request.Context(), rt); err != nil {
+			fmt.Printf("Error loading drivers: %v\n", err)
 		}
+		r.publishRouteEvent(c.Request.Context(), rt, "route.updated", nil)
 	}
 
-	// Publish event if confirmed
-	if rt.Status == "confirmed" {
-		event := map[string]interface{}{
-			"route_id": rt.ID,
-			"status":   "confirmed",
+	c.Header("ETag", routeETag(rt.Version))
+	c.JSON(http.StatusOK, routeResponse{Route: rt, Warnings: warnings})
+}
+
+// RecomputeRoute refreshes the arrival schedule and distances of the stored
+// solution without changing the stop order. Stops of orders deleted since are
+// dropped and reported as warnings.
+func (r *Router) RecomputeRoute(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rt, err := r.Repo.GetRoute(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ifMatch(c, rt) {
+		return
+	}
+	plan, err := r.loadPlan(c.Request.Context(), rt, rt.SolutionJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rt.SolutionJSON = plan.Recompute(rt.SolutionJSON)
+	// Stops of deleted orders do not survive the rebuild
+	var warnings []validation.Violation
+	for _, pr := range plan.Routes {
+		if pr.Vehicle < 0 {
+			continue
 		}
-		if r.PubSub != nil {
-			// Fire and forget or log error
-			if err := r.PubSub.Publish(c.Request.Context(), "route-events", event); err != nil {
-				fmt.Printf("Failed to publish route.finalized: %v\n", err)
-			}
+		for _, oid := range pr.Missing {
+			warnings = append(warnings, validation.Violation{
+				Code: validation.CodeUnknownOrder, Severity: validation.SeverityWarning, VehicleDBID: pr.VehicleDBID, OrderID: oid,
+				Message: fmt.Sprintf("order %d no longer exists and was dropped from vehicle %d", oid, pr.VehicleDBID),
+			})
 		}
 	}
 
-	c.JSON(http.StatusOK, rt)
+	if err := r.Repo.UpdateRoute(c.Request.Context(), rt, db.RevisionManual, changedBy(c)); err != nil {
+		respondRouteWriteError(c, err)
+		return
+	}
+	c.Header("ETag", routeETag(rt.Version))
+	c.JSON(http.StatusOK, routeResponse{Route: rt, Warnings: warnings})
 }
 
 func (r *Router) ReprocessRoute(c *gin.Context) {
@@ -289,41 +576,47 @@ func (r *Router) ReprocessRoute(c *gin.Context) {
 		return
 	}
 
-	// Publish event
-	event := map[string]interface{}{
-		"route_id": id,
-		"action":   "reprocess",
+	rt, err := r.Repo.GetRoute(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	if r.PubSub != nil {
-		if err := r.PubSub.Publish(c.Request.Context(), "route-events", event); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to publish event: " + err.Error()})
-			return
-		}
-	} else {
-		// Just for dev convenience if pubsub not configured
-		fmt.Println("Warning: PubSub client not initialized")
+	// Reprocessing re-optimizes the route's own depot and day
+	run := &db.OptimizationRun{RequestedRouteID: &id, DepotID: rt.DepotID, Date: rt.RouteDate}
+	if err := r.queueOptimization(c.Request.Context(), run); err != nil {
+		respondQueueError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "reprocess requested"})
+	c.JSON(http.StatusAccepted, gin.H{"message": "reprocess requested", "id": run.ID, "status": run.Status})
 }
 
 func (r *Router) TriggerOptimization(c *gin.Context) {
-	// Publish event to trigger optimization for pending orders
-	event := map[string]interface{}{
-		"action": "reprocess",
-		// No route_id needed means "find all pending and optimize"
+	// No requested route means "find all pending and optimize",
+	// optionally for a single depot and for a day other than today
+	depotID, err := depotFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	if r.PubSub != nil {
-		if err := r.PubSub.Publish(c.Request.Context(), "route-events", event); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to publish event: " + err.Error()})
-			return
-		}
-	} else {
-		// Just for dev convenience if pubsub not configured
-		fmt.Println("Warning: PubSub client not initialized")
+	date, err := dateFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	run := &db.OptimizationRun{Date: date}
+	if depotID != 0 {
+		run.DepotID = &depotID
+	}
+	if err := r.queueOptimization(c.Request.Context(), run); err != nil {
+		respondQueueError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "optimization requested"})
+	c.JSON(http.StatusAccepted, gin.H{"message": "optimization requested", "id": run.ID, "status": run.Status})
 }
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// InsertOrders slots late pending orders into an existing draft route at
+// their cheapest feasible positions, leaving the current sequences intact.
+func (r *Router) InsertOrders(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req struct {
+		OrderIDs []int `json:"order_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := make(map[int]bool, len(req.OrderIDs))
+	var orderIDs []int
+	for _, oid := range req.OrderIDs {
+		if !seen[oid] {
+			seen[oid] = true
+			orderIDs = append(orderIDs, oid)
+		}
+	}
+	req.OrderIDs = orderIDs
+
+	ctx := c.Request.Context()
+	rt, err := r.Repo.GetRoute(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rt.Status != "draft" {
+		c.JSON(http.StatusConflict, gin.H{"error": "orders can only be inserted into draft routes"})
+		return
+	}
+	if !ifMatch(c, rt) {
+		return
+	}
+
+	newOrders, err := r.Repo.ListOrdersByIDs(ctx, req.OrderIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	found := make(map[int]string, len(newOrders))
+	for _, o := range newOrders {
+		found[o.ID] = o.Status
+	}
+	for _, oid := range req.OrderIDs {
+		status, ok := found[oid]
+		if !ok {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("order %d does not exist", oid)})
+			return
+		}
+		if status != "pending" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("order %d is %s, only pending orders can be inserted", oid, status)})
+			return
+		}
+	}
+
+	// Pickup-and-delivery pairs go in whole
+	for _, oid := range rt.SolutionJSON.OrderIDs() {
+		seen[oid] = true
+	}
+	for _, o := range newOrders {
+		if o.PairOrderID != nil && !seen[*o.PairOrderID] {
+			seen[*o.PairOrderID] = true
+			req.OrderIDs = append(req.OrderIDs, *o.PairOrderID)
+		}
+	}
+
+	orders, err := r.Repo.ListOrdersByIDs(ctx, append(rt.SolutionJSON.OrderIDs(), req.OrderIDs...))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Dated orders only go on routes of that day, as when optimizing
+	inserting := make(map[int]bool, len(req.OrderIDs))
+	for _, oid := range req.OrderIDs {
+		inserting[oid] = true
+	}
+	for _, o := range orders {
+		if inserting[o.ID] && o.DeliveryDate != nil && *o.DeliveryDate != rt.RouteDate {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("order %d is for delivery on %s, the route is for %s", o.ID, *o.DeliveryDate, rt.RouteDate)})
+			return
+		}
+	}
+	// Only the route's own depot can take the orders
+	depotID := 0
+	if rt.DepotID != nil {
+		depotID = *rt.DepotID
+	}
+	vehicles, err := r.Repo.ListVehicles(ctx, depotID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	plan, err := r.buildPlan(ctx, rt, rt.SolutionJSON, orders, vehicles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	unassigned := plan.Insert(req.OrderIDs)
+	rt.SolutionJSON = plan.Recompute(rt.SolutionJSON)
+
+	if err := r.Repo.UpdateRoute(ctx, rt, db.RevisionManual, changedBy(c)); err != nil {
+		respondRouteWriteError(c, err)
+		return
+	}
+	if err := r.Repo.RecruitOrdersToRoute(ctx, rt.ID, rt.SolutionJSON.OrderIDs()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	inserted := []int{}
+	if unassigned == nil {
+		unassigned = []db.UnassignedOrder{}
+	}
+	failed := make(map[int]bool, len(unassigned))
+	for _, u := range unassigned {
+		failed[u.OrderID] = true
+	}
+	for _, oid := range req.OrderIDs {
+		if !failed[oid] {
+			inserted = append(inserted, oid)
+		}
+	}
+	c.Header("ETag", routeETag(rt.Version))
+	c.JSON(http.StatusOK, gin.H{"route": rt, "inserted": inserted, "unassigned": unassigned})
+}
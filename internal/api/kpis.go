@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"route-go/internal/db"
+	"route-go/internal/kpi"
+	"route-go/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Router) GetRouteKPIs(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rt, err := r.Repo.GetRoute(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	plan, err := r.loadPlan(c.Request.Context(), rt, rt.SolutionJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"route_id": rt.ID, "kpis": kpi.Compute(plan)})
+}
+
+// EvaluateRoute scores a candidate solution against the stored one without
+// saving anything, so dispatchers can try edits before committing them.
+func (r *Router) EvaluateRoute(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req struct {
+		SolutionJSON *db.Solution `json:"solution_json" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rt, err := r.Repo.GetRoute(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := r.loadPlan(ctx, rt, rt.SolutionJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	candidate, violations, err := r.validateSolution(ctx, rt, *req.SolutionJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	mandatory, err := r.mandatoryViolations(ctx, rt, *req.SolutionJSON, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	violations = append(violations, mandatory...)
+
+	base := kpi.Compute(current)
+	proposed := kpi.Compute(candidate)
+	c.JSON(http.StatusOK, gin.H{
+		"route_id":      rt.ID,
+		"solution_json": candidate.Recompute(*req.SolutionJSON),
+		"kpis":          proposed,
+		"current_kpis":  base,
+		"deltas":        kpi.Compare(base, proposed),
+		"violations":    violations,
+		"valid":         !validation.HasErrors(violations),
+	})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"route-go/internal/db"
+	"route-go/internal/solver"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Router) GetOptimization(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	run, err := r.Repo.GetOptimizationRun(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "optimization not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// errNoOptimizer means neither the in-process solver nor Pub/Sub is set up.
+var errNoOptimizer = errors.New("no optimizer available: PubSub is not configured")
+
+// queueOptimization records a new run and hands it to the in-process solver,
+// or publishes it for the Python worker which reports back through the same row.
+func (r *Router) queueOptimization(ctx context.Context, run *db.OptimizationRun) error {
+	if err := r.Repo.CreateOptimizationRun(ctx, run); err != nil {
+		return err
+	}
+
+	if r.Optimizer != nil {
+		go r.runOptimizer(run)
+		return nil
+	}
+
+	event := map[string]interface{}{
+		"action": "reprocess",
+		"run_id": run.ID,
+	}
+	if run.RequestedRouteID != nil {
+		event["route_id"] = *run.RequestedRouteID
+	}
+	if run.DepotID != nil {
+		event["depot_id"] = *run.DepotID
+	}
+	event["date"] = run.Date
+
+	if r.PubSub == nil {
+		// Nothing would ever pick the run up
+		r.Repo.FailOptimizationRun(ctx, run.ID, errNoOptimizer.Error())
+		return errNoOptimizer
+	}
+	if err := r.PubSub.Publish(ctx, "route-events", event); err != nil {
+		r.Repo.FailOptimizationRun(ctx, run.ID, err.Error())
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// respondQueueError writes the response for a queueOptimization error.
+func respondQueueError(c *gin.Context, err error) {
+	if errors.Is(err, errNoOptimizer) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+func (r *Router) runOptimizer(run *db.OptimizationRun) {
+	ctx := context.Background()
+	runID := run.ID
+	if err := r.Repo.StartOptimizationRun(ctx, runID); err != nil {
+		fmt.Printf("Failed to start optimization run %d: %v\n", runID, err)
+	}
+
+	var req solver.Request
+	if run.DepotID != nil {
+		req.DepotID = *run.DepotID
+	}
+	req.Date, _ = time.Parse(db.DateLayout, run.Date)
+	res, err := r.Optimizer.Run(ctx, req)
+	if err != nil {
+		fmt.Printf("Optimization run %d failed: %v\n", runID, err)
+		if err := r.Repo.FailOptimizationRun(ctx, runID, err.Error()); err != nil {
+			fmt.Printf("Failed to record optimization failure: %v\n", err)
+		}
+		return
+	}
+	fmt.Printf("Optimization run %d: route %d, %d orders routed, %d dropped, objective %d\n",
+		runID, res.RouteID, len(res.Routed), len(res.Dropped), res.Objective)
+	if err := r.Repo.SucceedOptimizationRun(ctx, runID, res.RouteID, int64(res.Objective), res.Unassigned); err != nil {
+		fmt.Printf("Failed to record optimization result: %v\n", err)
+	}
+}
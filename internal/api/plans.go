@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"route-go/internal/db"
+	"route-go/internal/matrix"
+	"route-go/internal/solver"
+	"route-go/internal/validation"
+)
+
+// routeResponse is a route plus the warnings it was force-saved with
+type routeResponse struct {
+	*db.Route
+	Warnings []validation.Violation `json:"warnings,omitempty"`
+}
+
+func (r *Router) matrixProvider() matrix.Provider {
+	if r.Matrix != nil {
+		return r.Matrix
+	}
+	return matrix.Haversine{}
+}
+
+// routeDay is the day rt is planned for.
+func routeDay(rt *db.Route) time.Time {
+	if day, err := time.ParseInLocation(db.DateLayout, rt.RouteDate, time.Local); err == nil {
+		return day
+	}
+	return time.Now()
+}
+
+// loadPlan lays sol, a solution of rt, out on the live orders and vehicles it
+// references, with the travel matrix of rt's day loaded.
+func (r *Router) loadPlan(ctx context.Context, rt *db.Route, sol db.Solution) (*solver.Plan, error) {
+	orders, err := r.Repo.ListOrdersByIDs(ctx, sol.OrderIDs())
+	if err != nil {
+		return nil, err
+	}
+	// Deactivated vehicles still drive the routes planned before
+	all, err := r.Repo.ListVehicles(ctx, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[int]bool)
+	for _, vr := range sol.Vehicles {
+		used[vr.VehicleDBID] = true
+	}
+	var vehicles []db.Vehicle
+	for _, v := range all {
+		if used[v.ID] {
+			vehicles = append(vehicles, v)
+		}
+	}
+
+	return r.buildPlan(ctx, rt, sol, orders, vehicles)
+}
+
+func (r *Router) buildPlan(ctx context.Context, rt *db.Route, sol db.Solution, orders []db.Order, vehicles []db.Vehicle) (*solver.Plan, error) {
+	depots, err := r.Repo.ListDepots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	penalties, err := r.Repo.ListDropPenalties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The vehicles' hours and days off on the route's day, as when optimizing
+	day := routeDay(rt)
+	calendars, err := r.Repo.ListAvailabilityOn(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	plan := solver.NewPlan(sol, orders, vehicles)
+	plan.Problem.Penalties = penalties
+	solver.ApplyDepotHours(plan.Problem, vehicles, depots)
+	solver.ApplyAvailability(plan.Problem, vehicles, calendars, day)
+	if err := plan.Problem.LoadMatrix(ctx, matrix.OnDate(r.matrixProvider(), day)); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (r *Router) validateSolution(ctx context.Context, rt *db.Route, sol db.Solution) (*solver.Plan, []validation.Violation, error) {
+	plan, err := r.loadPlan(ctx, rt, sol)
+	if err != nil {
+		return nil, nil, err
+	}
+	confirmed, err := r.Repo.OrdersInOtherConfirmedRoutes(ctx, sol.OrderIDs(), rt.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plan, validation.Validate(sol, plan, confirmed), nil
+}
+
+// mandatoryViolations reports the mandatory orders rt would leave out with sol
+// as its solution: those taken out of it and, when confirming, those the
+// optimizer could not fit either.
+func (r *Router) mandatoryViolations(ctx context.Context, rt *db.Route, sol db.Solution, confirming bool) ([]validation.Violation, error) {
+	kept := make(map[int]bool)
+	for _, id := range sol.OrderIDs() {
+		kept[id] = true
+	}
+	var removed []int
+	for _, id := range rt.SolutionJSON.OrderIDs() {
+		if !kept[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	var missing []db.UnassignedOrder
+	if len(removed) > 0 {
+		orders, err := r.Repo.ListOrdersByIDs(ctx, removed)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range orders {
+			missing = append(missing, db.UnassignedOrder{OrderID: o.ID, Reason: "taken out of the route", Priority: o.Priority})
+		}
+	}
+	if confirming {
+		for _, u := range rt.Unassigned {
+			if !kept[u.OrderID] {
+				missing = append(missing, u)
+			}
+		}
+	}
+	return validation.Mandatory(missing), nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) ListDropPenalties(c *gin.Context) {
+	penalties, err := r.Repo.ListDropPenalties(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, penalties)
+}
+
+// SaveDropPenalties changes what the optimizer pays for leaving out an order
+// of a given priority, e.g. {"low": 50000}. Priorities not given are kept.
+func (r *Router) SaveDropPenalties(c *gin.Context) {
+	var penalties map[string]int
+	if err := c.ShouldBindJSON(&penalties); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.ValidateDropPenalties(penalties); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := r.Repo.SaveDropPenalties(c.Request.Context(), penalties); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.ListDropPenalties(c)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Router) ListRouteRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := r.Repo.GetRoute(ctx, id); errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	revisions, err := r.Repo.ListRouteRevisions(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// routeRevision loads the revision named by the request's :id and the given
+// revision number, responding on failure.
+func (r *Router) routeRevision(c *gin.Context, revStr string) (*db.RouteRevision, bool) {
+	var id, revision int
+	if _, err := fmt.Sscan(c.Param("id"), &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	if _, err := fmt.Sscan(revStr, &revision); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return nil, false
+	}
+	rev, err := r.Repo.GetRouteRevision(c.Request.Context(), id, revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("route %d has no revision %d", id, revision)})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return rev, true
+}
+
+func (r *Router) GetRouteRevision(c *gin.Context) {
+	rev, ok := r.routeRevision(c, c.Param("revision"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffRouteRevisions lists the stops added, removed and moved between the
+// revisions ?from= and ?to=.
+func (r *Router) DiffRouteRevisions(c *gin.Context) {
+	if c.Query("from") == "" || c.Query("to") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to revisions are required"})
+		return
+	}
+	from, ok := r.routeRevision(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := r.routeRevision(c, c.Query("to"))
+	if !ok {
+		return
+	}
+	diff := db.DiffSolutions(*from.SolutionJSON, *to.SolutionJSON)
+	diff.From, diff.To = from.Revision, to.Revision
+	c.JSON(http.StatusOK, diff)
+}
+
+// RollbackRoute saves an earlier revision's solution, and the orders it left
+// out, as a new rollback revision. Orders deleted since are dropped from both.
+// It is validated against live data like any edit, and the route's orders are
+// re-synced.
+func (r *Router) RollbackRoute(c *gin.Context) {
+	rev, ok := r.routeRevision(c, c.Param("revision"))
+	if !ok {
+		return
+	}
+	rt, err := r.Repo.GetRoute(c.Request.Context(), rev.RouteID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rt.Status != db.RouteDraft && rt.Status != db.RouteConfirmed {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("route is %s, only draft and confirmed routes can be rolled back", rt.Status)})
+		return
+	}
+	if !ifMatch(c, rt) {
+		return
+	}
+	ids := rev.SolutionJSON.OrderIDs()
+	for _, u := range rev.Unassigned {
+		ids = append(ids, u.OrderID)
+	}
+	orders, err := r.Repo.ListOrdersByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	gone := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		gone[id] = true
+	}
+	for _, o := range orders {
+		delete(gone, o.ID)
+	}
+	sol := rev.SolutionJSON.WithoutOrders(gone)
+	rt.Unassigned = []db.UnassignedOrder{}
+	for _, u := range rev.Unassigned {
+		if !gone[u.OrderID] {
+			rt.Unassigned = append(rt.Unassigned, u)
+		}
+	}
+	r.saveRouteSolution(c, rt, &sol, db.RevisionRollback)
+}
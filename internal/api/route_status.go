@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// changedBy is who a request acts for, from the X-User header.
+func changedBy(c *gin.Context) string {
+	if user := c.GetHeader("X-User"); user != "" {
+		return user
+	}
+	return "anonymous"
+}
+
+// transitionRoute returns the handler moving a route to status to. A route
+// can only be confirmed when it is up to date and routes its mandatory orders.
+func (r *Router) transitionRoute(to string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		var id int
+		if _, err := fmt.Sscan(idStr, &id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		rt, err := r.Repo.GetRoute(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ifMatch(c, rt) {
+			return
+		}
+		if !db.CanTransition(rt.Status, to) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("route is %s and cannot become %s", rt.Status, to)})
+			return
+		}
+		if to == db.RouteConfirmed {
+			if rt.Stale {
+				c.JSON(http.StatusConflict, gin.H{"error": "route is stale, recompute or re-optimize it first"})
+				return
+			}
+			violations, err := r.mandatoryViolations(ctx, rt, rt.SolutionJSON, true)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if len(violations) > 0 {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "solution violates route constraints", "violations": violations})
+				return
+			}
+		}
+
+		change, err := r.Repo.TransitionRoute(ctx, id, rt.Version, to, changedBy(c))
+		if errors.Is(err, db.ErrIllegalTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			respondRouteWriteError(c, err)
+			return
+		}
+		// Reread for the new version and, once confirmed, the drivers
+		if rt, err = r.Repo.GetRoute(ctx, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		r.publishRouteEvent(ctx, rt, "route."+to, change)
+		c.Header("ETag", routeETag(rt.Version))
+		c.JSON(http.StatusOK, rt)
+	}
+}
+
+func (r *Router) ListRouteStatusChanges(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int
+	if _, err := fmt.Sscan(idStr, &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := r.Repo.GetRoute(ctx, id); errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	changes, err := r.Repo.ListRouteStatusChanges(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+// publishRouteEvent tells route-events subscribers about rt. eventType is
+// route.<status> for a status change, described by change, or route.updated
+// for an edit. Drivers are named once the route is confirmed.
+func (r *Router) publishRouteEvent(ctx context.Context, rt *db.Route, eventType string, change *db.RouteStatusChange) {
+	drivers := map[int]int{}
+	for _, vr := range rt.SolutionJSON.Vehicles {
+		if vr.Driver != nil {
+			drivers[vr.VehicleDBID] = vr.Driver.ID
+		}
+	}
+	event := map[string]interface{}{
+		"type":     eventType,
+		"route_id": rt.ID,
+		"status":   rt.Status,
+		"drivers":  drivers,
+	}
+	if change != nil {
+		event["from_status"] = change.FromStatus
+		event["changed_by"] = change.ChangedBy
+		event["changed_at"] = change.ChangedAt
+	}
+	if r.PubSub == nil {
+		return
+	}
+	// Fire and forget or log error
+	if err := r.PubSub.Publish(ctx, "route-events", event); err != nil {
+		fmt.Printf("Failed to publish %s: %v\n", eventType, err)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateShipment creates a pickup-and-delivery pair, e.g. a transfer between
+// two stores. Kinds are implied; the delivery takes the pickup's quantities.
+func (r *Router) CreateShipment(c *gin.Context) {
+	var s db.Shipment
+	if err := c.ShouldBindJSON(&s); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if s.Pickup.DepotID != nil && s.Delivery.DepotID != nil && *s.Pickup.DepotID != *s.Delivery.DepotID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pickup and delivery must belong to the same depot"})
+		return
+	}
+	if s.Delivery.DepotID == nil {
+		s.Delivery.DepotID = s.Pickup.DepotID
+	}
+	if s.Pickup.DepotID == nil {
+		s.Pickup.DepotID = s.Delivery.DepotID
+	}
+	// A date-scoped run must load both halves or neither
+	if s.Pickup.DeliveryDate != nil && s.Delivery.DeliveryDate != nil && *s.Pickup.DeliveryDate != *s.Delivery.DeliveryDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pickup and delivery must share the same delivery_date"})
+		return
+	}
+	if s.Delivery.DeliveryDate == nil {
+		s.Delivery.DeliveryDate = s.Pickup.DeliveryDate
+	}
+	if s.Pickup.DeliveryDate == nil {
+		s.Pickup.DeliveryDate = s.Delivery.DeliveryDate
+	}
+	dims, ok := r.capacityDimensions(c)
+	if !ok {
+		return
+	}
+	if err := s.Pickup.Quantities.Validate(dims); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("pickup: %v", err)})
+		return
+	}
+	for _, o := range []db.Order{s.Pickup, s.Delivery} {
+		if err := validDeliveryDate(o); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := r.Repo.CreateShipment(c.Request.Context(), &s); err != nil {
+		respondCreateOrderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"route-go/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeETag is the strong ETag of a route version.
+func routeETag(version int) string {
+	return fmt.Sprintf("%q", fmt.Sprint(version))
+}
+
+// ifMatch checks the If-Match header against the route read, answering 409
+// with the current version on a mismatch. Without the header, or with "*",
+// any version matches; the write is still conditional on the version read.
+func ifMatch(c *gin.Context, rt *db.Route) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == routeETag(rt.Version) {
+			return true
+		}
+	}
+	respondVersionConflict(c, rt.Version)
+	return false
+}
+
+func respondVersionConflict(c *gin.Context, current int) {
+	c.Header("ETag", routeETag(current))
+	c.JSON(http.StatusConflict, gin.H{"error": "route was modified by someone else, reload it and try again", "version": current})
+}
+
+// respondRouteWriteError maps a failed route write: a stale version is a
+// conflict.
+func respondRouteWriteError(c *gin.Context, err error) {
+	var conflict *db.VersionConflictError
+	if errors.As(err, &conflict) {
+		respondVersionConflict(c, conflict.Current)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
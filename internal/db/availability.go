@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// DateLayout is how calendar dates travel through the API (YYYY-MM-DD).
+const DateLayout = "2006-01-02"
+
+// Shift is a vehicle's working hours on a weekday (0 = Sunday, as in
+// Postgres' EXTRACT(DOW)), in minutes from midnight.
+type Shift struct {
+	Weekday  int `json:"weekday" binding:"min=0,max=6"`
+	StartsAt int `json:"starts_at" binding:"min=0,max=1440"`
+	EndsAt   int `json:"ends_at" binding:"min=0,max=1440"`
+}
+
+// AvailabilityException overrides the weekly shifts on a single date: a day
+// off (maintenance, holidays) or different hours. Hours left nil fall back
+// to the weekday's shift.
+type AvailabilityException struct {
+	Date      string `json:"date"`
+	Available bool   `json:"available"`
+	StartsAt  *int   `json:"starts_at" binding:"omitempty,min=0,max=1440"`
+	EndsAt    *int   `json:"ends_at" binding:"omitempty,min=0,max=1440"`
+	Reason    string `json:"reason"`
+}
+
+// Availability is a vehicle's calendar. Vehicles without shifts work all
+// day, every day, unless an exception says otherwise.
+type Availability struct {
+	VehicleID  int                     `json:"vehicle_id"`
+	Shifts     []Shift                 `json:"shifts"`
+	Exceptions []AvailabilityException `json:"exceptions"`
+}
+
+// HoursOn returns the vehicle's working hours on date; ok is false when it
+// does not work that day.
+func (a *Availability) HoursOn(date time.Time) (start, end int, ok bool) {
+	start, end, ok = 0, 1440, len(a.Shifts) == 0
+	for _, s := range a.Shifts {
+		if s.Weekday == int(date.Weekday()) {
+			start, end, ok = s.StartsAt, s.EndsAt, true
+		}
+	}
+	day := date.Format(DateLayout)
+	for _, e := range a.Exceptions {
+		if e.Date != day {
+			continue
+		}
+		if !e.Available {
+			return 0, 0, false
+		}
+		ok = true
+		if e.StartsAt != nil {
+			start = *e.StartsAt
+		}
+		if e.EndsAt != nil {
+			end = *e.EndsAt
+		}
+	}
+	return start, end, ok
+}
+
+func (r *Repository) GetAvailability(ctx context.Context, vehicleID int) (*Availability, error) {
+	all, err := r.listAvailability(ctx, vehicleID, "")
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := all[vehicleID]; ok {
+		return a, nil
+	}
+	return &Availability{VehicleID: vehicleID, Shifts: []Shift{}, Exceptions: []AvailabilityException{}}, nil
+}
+
+// ListAvailabilityOn returns the calendars of all vehicles that have one,
+// with only the exceptions for date.
+func (r *Repository) ListAvailabilityOn(ctx context.Context, date time.Time) (map[int]*Availability, error) {
+	return r.listAvailability(ctx, 0, date.Format(DateLayout))
+}
+
+// listAvailability loads the shifts and exceptions of vehicleID (all
+// vehicles when 0), keeping only the exceptions for date when given.
+func (r *Repository) listAvailability(ctx context.Context, vehicleID int, date string) (map[int]*Availability, error) {
+	out := make(map[int]*Availability)
+	get := func(id int) *Availability {
+		if out[id] == nil {
+			out[id] = &Availability{VehicleID: id, Shifts: []Shift{}, Exceptions: []AvailabilityException{}}
+		}
+		return out[id]
+	}
+
+	rows, err := r.Pool.Query(ctx, "SELECT vehicle_id, weekday, starts_at, ends_at FROM vehicle_shifts WHERE $1 = 0 OR vehicle_id = $1 ORDER BY vehicle_id, weekday", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var s Shift
+		if err := rows.Scan(&id, &s.Weekday, &s.StartsAt, &s.EndsAt); err != nil {
+			return nil, err
+		}
+		a := get(id)
+		a.Shifts = append(a.Shifts, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.Pool.Query(ctx, `SELECT vehicle_id, date::text, available, starts_at, ends_at, reason FROM vehicle_availability_exceptions
+		WHERE ($1 = 0 OR vehicle_id = $1) AND ($2 = '' OR date = NULLIF($2, '')::date)
+		ORDER BY vehicle_id, date`, vehicleID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var e AvailabilityException
+		if err := rows.Scan(&id, &e.Date, &e.Available, &e.StartsAt, &e.EndsAt, &e.Reason); err != nil {
+			return nil, err
+		}
+		a := get(id)
+		a.Exceptions = append(a.Exceptions, e)
+	}
+	return out, rows.Err()
+}
+
+// ReplaceShifts sets the vehicle's weekly shifts; an empty list makes it
+// available every day again.
+func (r *Repository) ReplaceShifts(ctx context.Context, vehicleID int, shifts []Shift) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM vehicle_shifts WHERE vehicle_id = $1", vehicleID); err != nil {
+		return err
+	}
+	for _, s := range shifts {
+		if _, err := tx.Exec(ctx, "INSERT INTO vehicle_shifts (vehicle_id, weekday, starts_at, ends_at) VALUES ($1, $2, $3, $4)", vehicleID, s.Weekday, s.StartsAt, s.EndsAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repository) SaveAvailabilityException(ctx context.Context, vehicleID int, e *AvailabilityException) error {
+	_, err := r.Pool.Exec(ctx, `INSERT INTO vehicle_availability_exceptions (vehicle_id, date, available, starts_at, ends_at, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (vehicle_id, date) DO UPDATE SET available = EXCLUDED.available, starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at, reason = EXCLUDED.reason`,
+		vehicleID, e.Date, e.Available, e.StartsAt, e.EndsAt, e.Reason)
+	return err
+}
+
+// DeleteAvailabilityException returns pgx.ErrNoRows when there is none.
+func (r *Repository) DeleteAvailabilityException(ctx context.Context, vehicleID int, date string) error {
+	var deleted int
+	return r.Pool.QueryRow(ctx, "DELETE FROM vehicle_availability_exceptions WHERE vehicle_id = $1 AND date = $2 RETURNING vehicle_id", vehicleID, date).Scan(&deleted)
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// DimensionWeight is the capacity dimension behind the legacy single-number
+// fields: Vehicle.Capacity, Order.Demand and Customer.Demand.
+const DimensionWeight = "weight"
+
+// Quantities are amounts per capacity dimension, e.g. {"weight": 800, "volume": 12}.
+// A vehicle without a capacity for a dimension is not limited in it; an
+// order without a quantity for it needs none.
+type Quantities map[string]int
+
+// CapacityDimension is a configured dimension and the unit it is counted in.
+type CapacityDimension struct {
+	Name string `json:"name" binding:"required"`
+	Unit string `json:"unit" binding:"required"`
+}
+
+// withWeight merges the legacy weight field into q. The weight dimension
+// wins whenever q has it, zero included; without it the legacy field fills it
+// in, so old clients that only know about that field keep working.
+func withWeight(q Quantities, weight *int) Quantities {
+	out := Quantities{}
+	for k, v := range q {
+		out[k] = v
+	}
+	if w, ok := out[DimensionWeight]; ok {
+		*weight = w
+	} else {
+		out[DimensionWeight] = *weight
+	}
+	return out
+}
+
+// extra is what gets stored in the JSONB column: everything but weight,
+// which has its own column.
+func (q Quantities) extra() Quantities {
+	out := Quantities{}
+	for k, v := range q {
+		if k != DimensionWeight {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Validate rejects negative amounts and dimensions that are not configured.
+func (q Quantities) Validate(dimensions []CapacityDimension) error {
+	known := make(map[string]bool, len(dimensions))
+	for _, d := range dimensions {
+		known[d.Name] = true
+	}
+	for name, v := range q {
+		if !known[name] {
+			return fmt.Errorf("unknown capacity dimension %q", name)
+		}
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+func (r *Repository) ListCapacityDimensions(ctx context.Context) ([]CapacityDimension, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT name, unit FROM capacity_dimensions ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var dims []CapacityDimension
+	for rows.Next() {
+		var d CapacityDimension
+		if err := rows.Scan(&d.Name, &d.Unit); err != nil {
+			return nil, err
+		}
+		dims = append(dims, d)
+	}
+	return dims, rows.Err()
+}
+
+// SaveCapacityDimension adds a dimension or changes its unit.
+func (r *Repository) SaveCapacityDimension(ctx context.Context, d *CapacityDimension) error {
+	_, err := r.Pool.Exec(ctx, "INSERT INTO capacity_dimensions (name, unit) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET unit = EXCLUDED.unit", d.Name, d.Unit)
+	return err
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithWeight(t *testing.T) {
+	tests := []struct {
+		name       string
+		q          Quantities
+		weight     int
+		want       Quantities
+		wantWeight int
+	}{
+		{"legacy only", nil, 5, Quantities{DimensionWeight: 5}, 5},
+		{"dimension only", Quantities{DimensionWeight: 7}, 0, Quantities{DimensionWeight: 7}, 7},
+		{"dimension wins", Quantities{DimensionWeight: 7}, 5, Quantities{DimensionWeight: 7}, 7},
+		{"dimension set to zero", Quantities{DimensionWeight: 0}, 5, Quantities{DimensionWeight: 0}, 0},
+		{"other dimensions kept", Quantities{"volume": 3}, 5, Quantities{"volume": 3, DimensionWeight: 5}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weight := tt.weight
+			if got := withWeight(tt.q, &weight); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withWeight = %v, want %v", got, tt.want)
+			}
+			if weight != tt.wantWeight {
+				t.Errorf("weight = %d, want %d", weight, tt.wantWeight)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Customer statuses. Inactive customers keep their order history but take
+// no new orders.
+const (
+	CustomerActive   = "active"
+	CustomerInactive = "inactive"
+)
+
+type Customer struct {
+	ID              int     `json:"id"`
+	Name            string  `json:"name" binding:"required"`
+	Lat             float64 `json:"lat" binding:"min=-90,max=90"`
+	Lon             float64 `json:"lon" binding:"min=-180,max=180"`
+	Demand          int     `json:"demand" binding:"min=0"`
+	TimeWindows     any     `json:"time_windows"` // Keeping as raw JSON for now or []map[string]int
+	ServiceDuration int     `json:"service_duration" binding:"min=0"`
+	// Default quantities per dimension for the customer's orders, including weight (Demand)
+	Quantities     Quantities `json:"quantities"`
+	RequiredSkills []string   `json:"required_skills"`
+	Status         string     `json:"status" binding:"omitempty,oneof=active inactive"`
+	UpdatedAt      string     `json:"updated_at"`
+	// Meters from the point of a proximity search, nil otherwise
+	DistanceM *float64 `json:"distance_m,omitempty"`
+}
+
+// ErrCustomerNotFound is returned when an order references a customer that
+// does not exist.
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// ErrCustomerInactive is returned when an order references a deactivated
+// customer.
+var ErrCustomerInactive = errors.New("customer is inactive")
+
+const customerColumns = "id, name, lat, lon, demand, time_windows, service_duration, quantities, required_skills, status, updated_at::text"
+
+// scanCustomer scans customerColumns, followed by any extra columns into
+// extra.
+func scanCustomer(row pgx.Row, c *Customer, extra ...any) error {
+	dest := append([]any{&c.ID, &c.Name, &c.Lat, &c.Lon, &c.Demand, &c.TimeWindows, &c.ServiceDuration, &c.Quantities, &c.RequiredSkills, &c.Status, &c.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	c.Quantities = withWeight(c.Quantities, &c.Demand)
+	return nil
+}
+
+func (c *Customer) normalize() {
+	c.Quantities = withWeight(c.Quantities, &c.Demand)
+	c.RequiredSkills = normalizeSkills(c.RequiredSkills)
+}
+
+// CreateCustomer creates c, active unless its status says otherwise.
+func (r *Repository) CreateCustomer(ctx context.Context, c *Customer) error {
+	c.normalize()
+	if c.Status == "" {
+		c.Status = CustomerActive
+	}
+	return r.Pool.QueryRow(ctx, "INSERT INTO customers (name, lat, lon, demand, time_windows, service_duration, quantities, required_skills, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, updated_at::text",
+		c.Name, c.Lat, c.Lon, c.Demand, c.TimeWindows, c.ServiceDuration, c.Quantities.extra(), c.RequiredSkills, c.Status).Scan(&c.ID, &c.UpdatedAt)
+}
+
+func (r *Repository) GetCustomer(ctx context.Context, id int) (*Customer, error) {
+	var c Customer
+	if err := scanCustomer(r.Pool.QueryRow(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = $1", id), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateCustomer returns pgx.ErrNoRows when the customer does not exist.
+// Orders already created keep the data they copied (see ListStaleOrders).
+func (r *Repository) UpdateCustomer(ctx context.Context, c *Customer) error {
+	c.normalize()
+	return r.Pool.QueryRow(ctx, "UPDATE customers SET name = $1, lat = $2, lon = $3, demand = $4, time_windows = $5, service_duration = $6, quantities = $7, required_skills = $8, status = $9 WHERE id = $10 RETURNING id, updated_at::text",
+		c.Name, c.Lat, c.Lon, c.Demand, c.TimeWindows, c.ServiceDuration, c.Quantities.extra(), c.RequiredSkills, c.Status, c.ID).Scan(&c.ID, &c.UpdatedAt)
+}
+
+// DeleteCustomer returns pgx.ErrNoRows when the customer does not exist, and
+// a foreign key violation when it has orders.
+func (r *Repository) DeleteCustomer(ctx context.Context, id int) error {
+	var deleted int
+	return r.Pool.QueryRow(ctx, "DELETE FROM customers WHERE id = $1 RETURNING id", id).Scan(&deleted)
+}
+
+// CustomerFilter narrows ListCustomers. Zero values match everything; a
+// proximity search needs both Lat and Lon.
+type CustomerFilter struct {
+	Name    string // case-insensitive substring
+	Status  string
+	Lat     *float64
+	Lon     *float64
+	RadiusM float64
+}
+
+// distanceExpr is the haversine distance in meters from ($3, $4), NULL
+// without a point.
+const distanceExpr = "CASE WHEN $3::float8 IS NULL OR $4::float8 IS NULL THEN NULL ELSE" +
+	" 2 * 6371000 * asin(sqrt(power(sin(radians(lat - $3) / 2), 2) + cos(radians($3)) * cos(radians(lat)) * power(sin(radians(lon - $4) / 2), 2))) END"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ListCustomers returns the customers matching f, nearest first for a
+// proximity search and by name otherwise.
+func (r *Repository) ListCustomers(ctx context.Context, f CustomerFilter) ([]Customer, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT * FROM (SELECT "+customerColumns+", "+distanceExpr+" AS distance_m FROM customers"+
+		" WHERE name ILIKE '%' || $1 || '%' AND ($2 = '' OR status = $2)) c"+
+		" WHERE $5::float8 IS NULL OR distance_m <= $5 ORDER BY distance_m, name, id",
+		likeEscaper.Replace(f.Name), f.Status, f.Lat, f.Lon, radius(f))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var customers []Customer
+	for rows.Next() {
+		var c Customer
+		if err := scanCustomer(rows, &c, &c.DistanceM); err != nil {
+			return nil, err
+		}
+		customers = append(customers, c)
+	}
+	return customers, rows.Err()
+}
+
+// radius is the search radius, nil (unbounded) without a point or a radius.
+func radius(f CustomerFilter) *float64 {
+	if f.Lat == nil || f.Lon == nil || f.RadiusM <= 0 {
+		return nil
+	}
+	return &f.RadiusM
+}
+
+// ListCustomerOrders returns the customer's orders, newest first.
+func (r *Repository) ListCustomerOrders(ctx context.Context, customerID int) ([]Order, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT "+orderColumns+" FROM orders WHERE customer_id = $1 ORDER BY created_at DESC, id DESC", customerID)
+	if err != nil {
+		return nil, err
+	}
+	return scanOrders(rows)
+}
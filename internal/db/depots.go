@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+)
+
+// Depot is a warehouse vehicles leave from. Opening hours are minutes from
+// midnight and bound when its vehicles may leave and must be back.
+type Depot struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name" binding:"required"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	OpensAt  int     `json:"opens_at" binding:"min=0,max=1440"`
+	ClosesAt int     `json:"closes_at" binding:"min=0,max=1440"`
+}
+
+func (r *Repository) CreateDepot(ctx context.Context, d *Depot) error {
+	return r.Pool.QueryRow(ctx, "INSERT INTO depots (name, lat, lon, opens_at, closes_at) VALUES ($1, $2, $3, $4, $5) RETURNING id", d.Name, d.Lat, d.Lon, d.OpensAt, d.ClosesAt).Scan(&d.ID)
+}
+
+func (r *Repository) GetDepot(ctx context.Context, id int) (*Depot, error) {
+	var d Depot
+	err := r.Pool.QueryRow(ctx, "SELECT id, name, lat, lon, opens_at, closes_at FROM depots WHERE id = $1", id).Scan(&d.ID, &d.Name, &d.Lat, &d.Lon, &d.OpensAt, &d.ClosesAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// UpdateDepot returns pgx.ErrNoRows when the depot does not exist.
+func (r *Repository) UpdateDepot(ctx context.Context, d *Depot) error {
+	return r.Pool.QueryRow(ctx, "UPDATE depots SET name = $1, lat = $2, lon = $3, opens_at = $4, closes_at = $5 WHERE id = $6 RETURNING id", d.Name, d.Lat, d.Lon, d.OpensAt, d.ClosesAt, d.ID).Scan(&d.ID)
+}
+
+// DeleteDepot returns pgx.ErrNoRows when the depot does not exist, and a
+// foreign key violation while vehicles, orders or routes still reference it.
+func (r *Repository) DeleteDepot(ctx context.Context, id int) error {
+	var deleted int
+	return r.Pool.QueryRow(ctx, "DELETE FROM depots WHERE id = $1 RETURNING id", id).Scan(&deleted)
+}
+
+func (r *Repository) ListDepots(ctx context.Context) ([]Depot, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT id, name, lat, lon, opens_at, closes_at FROM depots ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var depots []Depot
+	for rows.Next() {
+		var d Depot
+		if err := rows.Scan(&d.ID, &d.Name, &d.Lat, &d.Lon, &d.OpensAt, &d.ClosesAt); err != nil {
+			return nil, err
+		}
+		depots = append(depots, d)
+	}
+	return depots, rows.Err()
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"strings"
+)
+
+// Driver statuses. Only active drivers can be assigned to a vehicle.
+const (
+	DriverActive   = "active"
+	DriverInactive = "inactive"
+	DriverOnLeave  = "on_leave"
+)
+
+// Driver license categories (CNH): A for motorcycles, B to E for ever heavier
+// vehicles. A driver may hold A on top of another category, e.g. "AD".
+type Driver struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name" binding:"required"`
+	LicenseCategory string `json:"license_category" binding:"required,oneof=A B C D E AB AC AD AE"`
+	Phone           string `json:"phone"`
+	Status          string `json:"status" binding:"omitempty,oneof=active inactive on_leave"`
+}
+
+// LicenseCovers reports whether a driver holding category held may drive a
+// vehicle requiring required. Heavier categories include the lighter ones
+// from B up; A is separate.
+func LicenseCovers(held, required string) bool {
+	if required == "" {
+		return true
+	}
+	if required == "A" {
+		return strings.HasPrefix(held, "A")
+	}
+	top := strings.TrimPrefix(held, "A")
+	return top != "" && top >= required
+}
+
+// DriverAssignment puts a driver behind the wheel of a vehicle for a day.
+type DriverAssignment struct {
+	Date      string `json:"date" binding:"required"`
+	VehicleID int    `json:"vehicle_id" binding:"required"`
+	DriverID  int    `json:"driver_id" binding:"required"`
+}
+
+const driverColumns = "id, name, license_category, phone, status"
+
+func (r *Repository) CreateDriver(ctx context.Context, d *Driver) error {
+	if d.Status == "" {
+		d.Status = DriverActive
+	}
+	return r.Pool.QueryRow(ctx, "INSERT INTO drivers (name, license_category, phone, status) VALUES ($1, $2, $3, $4) RETURNING id", d.Name, d.LicenseCategory, d.Phone, d.Status).Scan(&d.ID)
+}
+
+func (r *Repository) GetDriver(ctx context.Context, id int) (*Driver, error) {
+	var d Driver
+	err := r.Pool.QueryRow(ctx, "SELECT "+driverColumns+" FROM drivers WHERE id = $1", id).Scan(&d.ID, &d.Name, &d.LicenseCategory, &d.Phone, &d.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// UpdateDriver returns pgx.ErrNoRows when the driver does not exist.
+func (r *Repository) UpdateDriver(ctx context.Context, d *Driver) error {
+	if d.Status == "" {
+		d.Status = DriverActive
+	}
+	return r.Pool.QueryRow(ctx, "UPDATE drivers SET name = $1, license_category = $2, phone = $3, status = $4 WHERE id = $5 RETURNING id", d.Name, d.LicenseCategory, d.Phone, d.Status, d.ID).Scan(&d.ID)
+}
+
+// DeleteDriver returns pgx.ErrNoRows when the driver does not exist, and a
+// foreign key violation when they have assignments.
+func (r *Repository) DeleteDriver(ctx context.Context, id int) error {
+	var deleted int
+	return r.Pool.QueryRow(ctx, "DELETE FROM drivers WHERE id = $1 RETURNING id", id).Scan(&deleted)
+}
+
+func (r *Repository) ListDrivers(ctx context.Context, status string) ([]Driver, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT "+driverColumns+" FROM drivers WHERE $1 = '' OR status = $1 ORDER BY name", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var drivers []Driver
+	for rows.Next() {
+		var d Driver
+		if err := rows.Scan(&d.ID, &d.Name, &d.LicenseCategory, &d.Phone, &d.Status); err != nil {
+			return nil, err
+		}
+		drivers = append(drivers, d)
+	}
+	return drivers, rows.Err()
+}
+
+// AssignDriver sets the vehicle's driver for the day, replacing any previous
+// one. A driver already driving another vehicle that day is a unique violation.
+func (r *Repository) AssignDriver(ctx context.Context, a *DriverAssignment) error {
+	_, err := r.Pool.Exec(ctx, `INSERT INTO driver_assignments (date, vehicle_id, driver_id) VALUES ($1, $2, $3)
+		ON CONFLICT (date, vehicle_id) DO UPDATE SET driver_id = EXCLUDED.driver_id`, a.Date, a.VehicleID, a.DriverID)
+	return err
+}
+
+// UnassignDriver returns pgx.ErrNoRows when the vehicle has no driver that day.
+func (r *Repository) UnassignDriver(ctx context.Context, date string, vehicleID int) error {
+	var deleted int
+	return r.Pool.QueryRow(ctx, "DELETE FROM driver_assignments WHERE date = $1 AND vehicle_id = $2 RETURNING vehicle_id", date, vehicleID).Scan(&deleted)
+}
+
+func (r *Repository) ListDriverAssignments(ctx context.Context, date string) ([]DriverAssignment, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT date::text, vehicle_id, driver_id FROM driver_assignments WHERE date = $1 ORDER BY vehicle_id", date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var assignments []DriverAssignment
+	for rows.Next() {
+		var a DriverAssignment
+		if err := rows.Scan(&a.Date, &a.VehicleID, &a.DriverID); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// AttachDrivers fills in the driver of each vehicle route of a route
+// confirmed or beyond, from the assignments on the route's date. Drivers are
+// never stored in solution_json.
+func (r *Repository) AttachDrivers(ctx context.Context, rt *Route) error {
+	return r.attachDrivers(ctx, []*Route{rt})
+}
+
+// attachDrivers is AttachDrivers for many routes in one query.
+func (r *Repository) attachDrivers(ctx context.Context, routes []*Route) error {
+	var ids []int
+	for _, rt := range routes {
+		if IsCommitted(rt.Status) {
+			ids = append(ids, rt.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	rows, err := r.Pool.Query(ctx, `SELECT rt.id, da.vehicle_id, d.id, d.name, d.license_category, d.phone, d.status
+		FROM routes rt JOIN driver_assignments da ON da.date = rt.route_date JOIN drivers d ON d.id = da.driver_id
+		WHERE rt.id = ANY($1)`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	type key struct{ routeID, vehicleID int }
+	drivers := make(map[key]*Driver)
+	for rows.Next() {
+		var k key
+		var d Driver
+		if err := rows.Scan(&k.routeID, &k.vehicleID, &d.ID, &d.Name, &d.LicenseCategory, &d.Phone, &d.Status); err != nil {
+			return err
+		}
+		drivers[k] = &d
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, rt := range routes {
+		if !IsCommitted(rt.Status) {
+			continue
+		}
+		for i := range rt.SolutionJSON.Vehicles {
+			vr := &rt.SolutionJSON.Vehicles[i]
+			vr.Driver = drivers[key{rt.ID, vr.VehicleDBID}]
+		}
+	}
+	return nil
+}
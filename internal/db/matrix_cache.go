@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+)
+
+// MatrixEntry is a cached distance/duration between two coordinate keys.
+type MatrixEntry struct {
+	FromKey     string
+	ToKey       string
+	DistanceM   int
+	DurationMin int
+}
+
+// LookupMatrixEntries returns the entries between keys cached for the
+// planning date (YYYY-MM-DD).
+func (r *Repository) LookupMatrixEntries(ctx context.Context, provider, date string, keys []string) ([]MatrixEntry, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT from_key, to_key, distance_m, duration_min FROM matrix_cache
+		WHERE provider = $1 AND plan_date = $2 AND from_key = ANY($3) AND to_key = ANY($3)`, provider, date, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []MatrixEntry
+	for rows.Next() {
+		var e MatrixEntry
+		if err := rows.Scan(&e.FromKey, &e.ToKey, &e.DistanceM, &e.DurationMin); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *Repository) SaveMatrixEntries(ctx context.Context, provider, date string, entries []MatrixEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	from := make([]string, len(entries))
+	to := make([]string, len(entries))
+	dist := make([]int, len(entries))
+	dur := make([]int, len(entries))
+	for i, e := range entries {
+		from[i], to[i], dist[i], dur[i] = e.FromKey, e.ToKey, e.DistanceM, e.DurationMin
+	}
+	_, err := r.Pool.Exec(ctx, `INSERT INTO matrix_cache (provider, plan_date, from_key, to_key, distance_m, duration_min, computed_at)
+		SELECT $1, $2, f, t, d, m, CURRENT_TIMESTAMP FROM unnest($3::text[], $4::text[], $5::int[], $6::int[]) AS x(f, t, d, m)
+		ON CONFLICT (provider, plan_date, from_key, to_key) DO UPDATE
+		SET distance_m = EXCLUDED.distance_m, duration_min = EXCLUDED.duration_min, computed_at = EXCLUDED.computed_at`,
+		provider, date, from, to, dist, dur)
+	return err
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+)
+
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+type OptimizationRun struct {
+	ID               int     `json:"id"`
+	Status           string  `json:"status"`
+	RequestedRouteID *int    `json:"requested_route_id"`
+	DepotID          *int    `json:"depot_id"`
+	Date             string  `json:"date"` // planned day, YYYY-MM-DD
+	RouteID          *int    `json:"route_id"`
+	ObjectiveValue   *int64  `json:"objective_value"`
+	Error            *string `json:"error"`
+	CreatedAt        string  `json:"created_at"`
+	StartedAt        *string `json:"started_at"`
+	FinishedAt       *string `json:"finished_at"`
+	// Unassigned lists the orders a successful run could not route, and why
+	Unassigned []UnassignedOrder `json:"unassigned"`
+}
+
+type UnassignedOrder struct {
+	OrderID  int    `json:"order_id"`
+	Reason   string `json:"reason"`
+	Priority string `json:"priority,omitempty"`
+}
+
+// IsMandatory reports whether the order had to be routed.
+func (u UnassignedOrder) IsMandatory() bool {
+	return u.Priority == PriorityMandatory
+}
+
+// stillUnassigned drops the orders sol now serves from unassigned.
+func stillUnassigned(unassigned []UnassignedOrder, sol Solution) []UnassignedOrder {
+	routed := make(map[int]bool)
+	for _, id := range sol.OrderIDs() {
+		routed[id] = true
+	}
+	left := []UnassignedOrder{}
+	for _, u := range unassigned {
+		if !routed[u.OrderID] {
+			left = append(left, u)
+		}
+	}
+	return left
+}
+
+func (r *Repository) CreateOptimizationRun(ctx context.Context, run *OptimizationRun) error {
+	return r.Pool.QueryRow(ctx, "INSERT INTO optimization_runs (status, requested_route_id, depot_id, plan_date) VALUES ($1, $2, $3, COALESCE(NULLIF($4, '')::date, CURRENT_DATE)) RETURNING id, status, plan_date::text, created_at::text",
+		RunQueued, run.RequestedRouteID, run.DepotID, run.Date).Scan(&run.ID, &run.Status, &run.Date, &run.CreatedAt)
+}
+
+func (r *Repository) GetOptimizationRun(ctx context.Context, id int) (*OptimizationRun, error) {
+	var run OptimizationRun
+	err := r.Pool.QueryRow(ctx, `SELECT id, status, requested_route_id, depot_id, plan_date::text, route_id, objective_value, error, created_at::text, started_at::text, finished_at::text, unassigned
+		FROM optimization_runs WHERE id = $1`, id).Scan(&run.ID, &run.Status, &run.RequestedRouteID, &run.DepotID, &run.Date, &run.RouteID, &run.ObjectiveValue, &run.Error, &run.CreatedAt, &run.StartedAt, &run.FinishedAt, &run.Unassigned)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *Repository) StartOptimizationRun(ctx context.Context, id int) error {
+	_, err := r.Pool.Exec(ctx, "UPDATE optimization_runs SET status = $1, started_at = CURRENT_TIMESTAMP WHERE id = $2", RunRunning, id)
+	return err
+}
+
+func (r *Repository) SucceedOptimizationRun(ctx context.Context, id, routeID int, objective int64, unassigned []UnassignedOrder) error {
+	if unassigned == nil {
+		unassigned = []UnassignedOrder{}
+	}
+	_, err := r.Pool.Exec(ctx, "UPDATE optimization_runs SET status = $1, route_id = $2, objective_value = $3, unassigned = $4, finished_at = CURRENT_TIMESTAMP WHERE id = $5", RunSucceeded, routeID, objective, unassigned, id)
+	return err
+}
+
+func (r *Repository) FailOptimizationRun(ctx context.Context, id int, reason string) error {
+	_, err := r.Pool.Exec(ctx, "UPDATE optimization_runs SET status = $1, error = $2, finished_at = CURRENT_TIMESTAMP WHERE id = $3", RunFailed, reason, id)
+	return err
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrOrderLocked is returned when editing or deleting an order routed into a
+// route that is no longer a draft.
+var ErrOrderLocked = errors.New("order is in a confirmed route")
+
+func (r *Repository) GetOrder(ctx context.Context, id int) (*Order, error) {
+	var o Order
+	if err := scanOrder(r.Pool.QueryRow(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = $1", id), &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// lockOrder locks the order and its pair partner, if any, for the rest of tx
+// and returns their IDs together with the draft routes they are in. It returns
+// pgx.ErrNoRows when the order does not exist and ErrOrderLocked when either
+// order is in any other route.
+func lockOrder(ctx context.Context, tx pgx.Tx, id int) (*Order, []int, []int, error) {
+	var o Order
+	if err := scanOrder(tx.QueryRow(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = $1 FOR UPDATE", id), &o); err != nil {
+		return nil, nil, nil, err
+	}
+	ids := []int{o.ID}
+	if o.PairOrderID != nil {
+		ids = append(ids, *o.PairOrderID)
+	}
+	rows, err := tx.Query(ctx, "SELECT rt.id, rt.status FROM orders o JOIN routes rt ON rt.id = o.route_id WHERE o.id = ANY($1) FOR UPDATE", ids)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+	var drafts []int
+	for rows.Next() {
+		var routeID int
+		var status string
+		if err := rows.Scan(&routeID, &status); err != nil {
+			return nil, nil, nil, err
+		}
+		if status != "draft" {
+			return nil, nil, nil, fmt.Errorf("%w %d", ErrOrderLocked, routeID)
+		}
+		if len(drafts) == 0 || drafts[len(drafts)-1] != routeID {
+			drafts = append(drafts, routeID)
+		}
+	}
+	return &o, ids, drafts, rows.Err()
+}
+
+// UpdateOrder replaces the editable fields of o. The customer, kind and pair
+// cannot change; a pair shares its quantities, depot and delivery date, so
+// changing them on one half changes both. Draft routes holding either order
+// are marked stale.
+func (r *Repository) UpdateOrder(ctx context.Context, o *Order) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	cur, _, drafts, err := lockOrder(ctx, tx, o.ID)
+	if err != nil {
+		return err
+	}
+	o.Quantities = withWeight(o.Quantities, &o.Demand)
+	o.RequiredSkills = normalizeSkills(o.RequiredSkills)
+	if o.Priority == "" {
+		o.Priority = PriorityNormal
+	}
+	_, err = tx.Exec(ctx, `UPDATE orders SET customer_name = $1, lat = $2, lon = $3, demand = $4, time_windows = $5, service_duration = $6, depot_id = $7, quantities = $8,
+		required_skills = ARRAY(SELECT DISTINCT unnest($9::text[] || COALESCE((SELECT required_skills FROM customers WHERE id = orders.customer_id), '{}')) ORDER BY 1),
+		priority = $10, delivery_date = $11 WHERE id = $12`,
+		o.CustomerName, o.Lat, o.Lon, o.Demand, o.TimeWindows, o.ServiceDuration, o.DepotID, o.Quantities.extra(), o.RequiredSkills, o.Priority, o.DeliveryDate, o.ID)
+	if err != nil {
+		return err
+	}
+	if cur.PairOrderID != nil {
+		if _, err := tx.Exec(ctx, "UPDATE orders SET demand = $1, quantities = $2, depot_id = $3, delivery_date = $4 WHERE id = $5",
+			o.Demand, o.Quantities.extra(), o.DepotID, o.DeliveryDate, *cur.PairOrderID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, "UPDATE routes SET stale = TRUE WHERE id = ANY($1)", drafts); err != nil {
+		return err
+	}
+	if err := scanOrder(tx.QueryRow(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = $1", o.ID), o); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteOrder deletes the order, together with its pair partner since half a
+// pair cannot be served, and returns the IDs deleted. Their stops are removed
+// from the draft routes holding them, each saved as a new revision by author
+// and marked stale, and they are dropped from the unassigned lists of all
+// routes.
+func (r *Repository) DeleteOrder(ctx context.Context, id int, author string) ([]int, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, ids, drafts, err := lockOrder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	if _, err := tx.Exec(ctx, `UPDATE routes SET unassigned = (SELECT COALESCE(jsonb_agg(u), '[]'::jsonb) FROM jsonb_array_elements(unassigned) u WHERE (u->>'order_id')::int <> ALL($1))
+		WHERE EXISTS (SELECT 1 FROM jsonb_array_elements(unassigned) u WHERE (u->>'order_id')::int = ANY($1))`, ids); err != nil {
+		return nil, err
+	}
+	for _, routeID := range drafts {
+		var sol Solution
+		var unassigned []UnassignedOrder
+		if err := tx.QueryRow(ctx, "SELECT solution_json, unassigned FROM routes WHERE id = $1", routeID).Scan(&sol, &unassigned); err != nil {
+			return nil, err
+		}
+		sol = sol.WithoutOrders(deleted)
+		if _, err := tx.Exec(ctx, "UPDATE routes SET solution_json = $1, stale = TRUE WHERE id = $2", sol, routeID); err != nil {
+			return nil, err
+		}
+		if err := insertRevision(ctx, tx, routeID, sol, unassigned, RevisionManual, author); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx, "UPDATE orders SET pair_order_id = NULL WHERE id = ANY($1)", ids); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM orders WHERE id = ANY($1)", ids); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit(ctx)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Order priorities, from never to be left out to first to go.
+const (
+	PriorityMandatory = "mandatory"
+	PriorityHigh      = "high"
+	PriorityNormal    = "normal"
+	PriorityLow       = "low"
+)
+
+// DefaultDropPenalties mirrors the seed in schema.sql. Normal is the penalty
+// every order had before priorities existed.
+var DefaultDropPenalties = map[string]int{
+	PriorityMandatory: 100000000,
+	PriorityHigh:      5000000,
+	PriorityNormal:    1000000,
+	PriorityLow:       200000,
+}
+
+// ListDropPenalties returns the optimizer's cost of leaving out an order, by
+// priority. Priorities without a row keep their default.
+func (r *Repository) ListDropPenalties(ctx context.Context) (map[string]int, error) {
+	penalties := make(map[string]int, len(DefaultDropPenalties))
+	for p, v := range DefaultDropPenalties {
+		penalties[p] = v
+	}
+	rows, err := r.Pool.Query(ctx, "SELECT priority, penalty FROM drop_penalties")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var priority string
+		var penalty int
+		if err := rows.Scan(&priority, &penalty); err != nil {
+			return nil, err
+		}
+		penalties[priority] = penalty
+	}
+	return penalties, rows.Err()
+}
+
+// ValidateDropPenalties checks that penalties only names known priorities,
+// with positive penalties.
+func ValidateDropPenalties(penalties map[string]int) error {
+	for priority, penalty := range penalties {
+		if _, ok := DefaultDropPenalties[priority]; !ok {
+			return fmt.Errorf("unknown priority %q", priority)
+		}
+		if penalty <= 0 {
+			return fmt.Errorf("penalty for %s must be positive", priority)
+		}
+	}
+	return nil
+}
+
+// SaveDropPenalties updates the penalties of the given priorities.
+func (r *Repository) SaveDropPenalties(ctx context.Context, penalties map[string]int) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for priority, penalty := range penalties {
+		_, err := tx.Exec(ctx, "INSERT INTO drop_penalties (priority, penalty) VALUES ($1, $2) ON CONFLICT (priority) DO UPDATE SET penalty = EXCLUDED.penalty", priority, penalty)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -2,9 +2,11 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -29,87 +31,217 @@ func (r *Repository) InitSchema(schemaPath string) error {
 	return err
 }
 
+// Route modes: a vehicle either comes back (to its end location, or its
+// start when it has none) or finishes at its last stop.
+const (
+	RouteModeReturn = "return"
+	RouteModeOpen   = "open"
+)
+
 type Vehicle struct {
-	ID       int     `json:"id"`
-	Name     string  `json:"name"`
-	Capacity int     `json:"capacity"`
-	StartLat float64 `json:"start_lat"`
-	StartLon float64 `json:"start_lon"`
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Capacity  int      `json:"capacity"` // The weight dimension of Capacities
+	StartLat  float64  `json:"start_lat"`
+	StartLon  float64  `json:"start_lon"`
+	EndLat    *float64 `json:"end_lat"` // Where the route ends, the start location when nil
+	EndLon    *float64 `json:"end_lon"`
+	RouteMode string   `json:"route_mode" binding:"omitempty,oneof=return open"`
+	DepotID   *int     `json:"depot_id"` // Home depot
+	// License category its drivers need, empty for any
+	RequiredLicense string `json:"required_license" binding:"omitempty,oneof=A B C D E"`
+	// Capacity per dimension, including weight
+	Capacities Quantities `json:"capacities"`
+	Skills     []string   `json:"skills"`
+	// Inactive vehicles are retired: left out of listings and optimization
+	Active bool `json:"active"`
 }
 
-type Customer struct {
-	ID              int     `json:"id"`
-	Name            string  `json:"name"`
-	Lat             float64 `json:"lat"`
-	Lon             float64 `json:"lon"`
-	Demand          int     `json:"demand"`
-	TimeWindows     any     `json:"time_windows"` // Keeping as raw JSON for now or []map[string]int
-	ServiceDuration int     `json:"service_duration"`
+// EndLocation is where a returning vehicle finishes its route.
+func (v Vehicle) EndLocation() (float64, float64) {
+	if v.EndLat != nil && v.EndLon != nil {
+		return *v.EndLat, *v.EndLon
+	}
+	return v.StartLat, v.StartLon
 }
 
 type Route struct {
-	ID           int    `json:"id"`
-	SolutionJSON any    `json:"solution_json"`
-	CreatedAt    string `json:"created_at"`
-	Status       string `json:"status"`
+	ID           int      `json:"id"`
+	SolutionJSON Solution `json:"solution_json"`
+	CreatedAt    string   `json:"created_at"`
+	Status       string   `json:"status"`
+	DepotID      *int     `json:"depot_id"`
+	// Orders the last optimization could not fit, minus those routed since
+	Unassigned []UnassignedOrder `json:"unassigned"`
+	// YYYY-MM-DD, today when created without one
+	RouteDate string `json:"route_date"`
+	// An order of the route changed or was deleted since it was planned
+	Stale bool `json:"stale"`
+	// Bumped by every write, served as the route's ETag
+	Version int `json:"version"`
+}
+
+// VersionConflictError is returned when writing a route that changed since
+// the version the writer read.
+type VersionConflictError struct {
+	RouteID int
+	Current int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("route %d was modified, current version is %d", e.RouteID, e.Current)
+}
+
+const vehicleColumns = "id, name, capacity, start_lat, start_lon, end_lat, end_lon, route_mode, depot_id, required_license, capacities, skills, active"
+
+func scanVehicle(row pgx.Row, v *Vehicle) error {
+	if err := row.Scan(&v.ID, &v.Name, &v.Capacity, &v.StartLat, &v.StartLon, &v.EndLat, &v.EndLon, &v.RouteMode, &v.DepotID, &v.RequiredLicense, &v.Capacities, &v.Skills, &v.Active); err != nil {
+		return err
+	}
+	v.Capacities = withWeight(v.Capacities, &v.Capacity)
+	return nil
+}
+
+// Vehicles without their own start location leave from their home depot
+func (r *Repository) defaultVehicleStart(ctx context.Context, v *Vehicle) error {
+	if v.DepotID == nil || v.StartLat != 0 || v.StartLon != 0 {
+		return nil
+	}
+	return r.Pool.QueryRow(ctx, "SELECT lat, lon FROM depots WHERE id = $1", *v.DepotID).Scan(&v.StartLat, &v.StartLon)
 }
 
 func (r *Repository) CreateVehicle(ctx context.Context, v *Vehicle) error {
-	_, err := r.Pool.Exec(ctx, "INSERT INTO vehicles (name, capacity, start_lat, start_lon) VALUES ($1, $2, $3, $4)", v.Name, v.Capacity, v.StartLat, v.StartLon)
+	if err := r.defaultVehicleStart(ctx, v); err != nil {
+		return err
+	}
+	if v.RouteMode == "" {
+		v.RouteMode = RouteModeReturn
+	}
+	v.Capacities = withWeight(v.Capacities, &v.Capacity)
+	v.Skills = normalizeSkills(v.Skills)
+	_, err := r.Pool.Exec(ctx, "INSERT INTO vehicles (name, capacity, start_lat, start_lon, end_lat, end_lon, route_mode, depot_id, required_license, capacities, skills, active) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		v.Name, v.Capacity, v.StartLat, v.StartLon, v.EndLat, v.EndLon, v.RouteMode, v.DepotID, v.RequiredLicense, v.Capacities.extra(), v.Skills, v.Active)
 	return err
 }
 
 func (r *Repository) GetVehicle(ctx context.Context, id int) (*Vehicle, error) {
 	var v Vehicle
-	err := r.Pool.QueryRow(ctx, "SELECT id, name, capacity, start_lat, start_lon FROM vehicles WHERE id = $1", id).Scan(&v.ID, &v.Name, &v.Capacity, &v.StartLat, &v.StartLon)
+	err := scanVehicle(r.Pool.QueryRow(ctx, "SELECT "+vehicleColumns+" FROM vehicles WHERE id = $1", id), &v)
 	if err != nil {
 		return nil, err
 	}
 	return &v, nil
 }
 
+// UpdateVehicle marks the draft routes using the vehicle stale when it is
+// deactivated.
 func (r *Repository) UpdateVehicle(ctx context.Context, v *Vehicle) error {
-	_, err := r.Pool.Exec(ctx, "UPDATE vehicles SET name = $1, capacity = $2, start_lat = $3, start_lon = $4 WHERE id = $5", v.Name, v.Capacity, v.StartLat, v.StartLon, v.ID)
-	return err
+	if err := r.defaultVehicleStart(ctx, v); err != nil {
+		return err
+	}
+	if v.RouteMode == "" {
+		v.RouteMode = RouteModeReturn
+	}
+	v.Capacities = withWeight(v.Capacities, &v.Capacity)
+	v.Skills = normalizeSkills(v.Skills)
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "UPDATE vehicles SET name = $1, capacity = $2, start_lat = $3, start_lon = $4, end_lat = $5, end_lon = $6, route_mode = $7, depot_id = $8, required_license = $9, capacities = $10, skills = $11, active = $12 WHERE id = $13",
+		v.Name, v.Capacity, v.StartLat, v.StartLon, v.EndLat, v.EndLon, v.RouteMode, v.DepotID, v.RequiredLicense, v.Capacities.extra(), v.Skills, v.Active, v.ID)
+	if err != nil {
+		return err
+	}
+	if !v.Active {
+		if _, err := tx.Exec(ctx, "UPDATE routes SET stale = TRUE WHERE status = 'draft' AND "+usesVehicle, v.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *Repository) ListVehicles(ctx context.Context) ([]Vehicle, error) {
-	rows, err := r.Pool.Query(ctx, "SELECT id, name, capacity, start_lat, start_lon FROM vehicles")
+// usesVehicle matches the routes whose solution has a route for vehicle $1.
+const usesVehicle = "solution_json->'vehicles' @> jsonb_build_array(jsonb_build_object('vehicle_db_id', $1::int))"
+
+// ErrVehicleInUse is returned when deleting a vehicle that a confirmed route
+// or one beyond it (see IsCommitted) still uses.
+var ErrVehicleInUse = errors.New("vehicle is in a confirmed route")
+
+// DeleteVehicle deletes the vehicle with its shifts, exceptions and driver
+// assignments, and returns the draft routes that used it, now marked stale.
+// It returns pgx.ErrNoRows when the vehicle does not exist.
+func (r *Repository) DeleteVehicle(ctx context.Context, id int) ([]int, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked int
+	if err := tx.QueryRow(ctx, "SELECT id FROM vehicles WHERE id = $1 FOR UPDATE", id).Scan(&locked); err != nil {
+		return nil, err
+	}
+	var confirmed []int
+	if err := tx.QueryRow(ctx, "SELECT COALESCE(array_agg(id ORDER BY id), '{}') FROM routes WHERE status IN "+committedStatuses+" AND "+usesVehicle, id).Scan(&confirmed); err != nil {
+		return nil, err
+	}
+	if len(confirmed) > 0 {
+		return nil, fmt.Errorf("%w %v, set active to false instead", ErrVehicleInUse, confirmed)
+	}
+	drafts := []int{}
+	rows, err := tx.Query(ctx, "UPDATE routes SET stale = TRUE WHERE status = 'draft' AND "+usesVehicle+" RETURNING id", id)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var vehicles []Vehicle
 	for rows.Next() {
-		var v Vehicle
-		if err := rows.Scan(&v.ID, &v.Name, &v.Capacity, &v.StartLat, &v.StartLon); err != nil {
+		var routeID int
+		if err := rows.Scan(&routeID); err != nil {
+			rows.Close()
 			return nil, err
 		}
-		vehicles = append(vehicles, v)
+		drafts = append(drafts, routeID)
 	}
-	return vehicles, nil
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM vehicles WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	return drafts, tx.Commit(ctx)
 }
 
-func (r *Repository) CreateCustomer(ctx context.Context, c *Customer) error {
-	_, err := r.Pool.Exec(ctx, "INSERT INTO customers (name, lat, lon, demand, time_windows, service_duration) VALUES ($1, $2, $3, $4, $5, $6)", c.Name, c.Lat, c.Lon, c.Demand, c.TimeWindows, c.ServiceDuration)
-	return err
-}
+// ListVehicles returns the active vehicles, or all of them with
+// includeInactive, of depotID only when non-zero.
+func (r *Repository) ListVehicles(ctx context.Context, depotID int, includeInactive bool) ([]Vehicle, error) {
+	query := "SELECT " + vehicleColumns + " FROM vehicles WHERE 1=1"
+	args := []interface{}{}
+	if depotID != 0 {
+		query += " AND depot_id = $1"
+		args = append(args, depotID)
+	}
+	if !includeInactive {
+		query += " AND active"
+	}
+	query += " ORDER BY id"
 
-func (r *Repository) ListCustomers(ctx context.Context) ([]Customer, error) {
-	rows, err := r.Pool.Query(ctx, "SELECT id, name, lat, lon, demand, time_windows, service_duration FROM customers")
+	rows, err := r.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var customers []Customer
+	var vehicles []Vehicle
 	for rows.Next() {
-		var c Customer
-		if err := rows.Scan(&c.ID, &c.Name, &c.Lat, &c.Lon, &c.Demand, &c.TimeWindows, &c.ServiceDuration); err != nil {
+		var v Vehicle
+		if err := scanVehicle(rows, &v); err != nil {
 			return nil, err
 		}
-		customers = append(customers, c)
+		vehicles = append(vehicles, v)
 	}
-	return customers, nil
+	return vehicles, nil
 }
 
 type Order struct {
@@ -124,15 +256,130 @@ type Order struct {
 	CreatedAt       string  `json:"created_at"`
 	Status          string  `json:"status"`
 	RouteID         *int    `json:"route_id"`
+	DepotID         *int    `json:"depot_id"` // Fulfilling depot, any depot when nil
+	// Quantity per capacity dimension, including weight (Demand)
+	Quantities Quantities `json:"quantities"`
+	// Skills the serving vehicle must have
+	RequiredSkills []string `json:"required_skills"`
+	Kind           string   `json:"kind" binding:"omitempty,oneof=delivery pickup"`
+	// The other half of a pickup-and-delivery pair, set by CreateShipment
+	PairOrderID *int `json:"pair_order_id"`
+	// How much leaving the order out costs the optimizer, normal when empty
+	Priority string `json:"priority" binding:"omitempty,oneof=mandatory high normal low"`
+	// YYYY-MM-DD, any day when nil
+	DeliveryDate *string `json:"delivery_date"`
+	// When the customer's master data was copied, nil without a customer
+	CustomerSyncedAt *string `json:"customer_synced_at"`
+}
+
+// inherit fills in whatever the order leaves blank from its customer's
+// master data; anything the order sets is a per-order override.
+func (o *Order) inherit(c Customer) {
+	if o.CustomerName == "" {
+		o.CustomerName = c.Name
+	}
+	if o.Lat == 0 && o.Lon == 0 {
+		o.Lat, o.Lon = c.Lat, c.Lon
+	}
+	if windows, ok := o.TimeWindows.([]any); o.TimeWindows == nil || (ok && len(windows) == 0) {
+		o.TimeWindows = c.TimeWindows
+	}
+	if o.ServiceDuration == 0 {
+		o.ServiceDuration = c.ServiceDuration
+	}
+	// Quantities are overridden as a whole
+	if o.Demand == 0 && len(o.Quantities) == 0 {
+		o.Demand, o.Quantities = c.Demand, c.Quantities
+	}
+}
+
+// Order kinds. A delivery without a pair is loaded at the depot; a pickup
+// without one (a return) is unloaded there.
+const (
+	OrderKindDelivery = "delivery"
+	OrderKindPickup   = "pickup"
+)
+
+// IsPickup reports whether the order is loaded at its own location.
+func (o Order) IsPickup() bool {
+	return o.Kind == OrderKindPickup
+}
+
+// orderSkillsExpr adds the customer's required skills ($1 is customer_id)
+// to the order's own ($10).
+const orderSkillsExpr = "ARRAY(SELECT DISTINCT unnest($10::text[] || COALESCE((SELECT required_skills FROM customers WHERE id = $1), '{}')) ORDER BY 1)"
+
+const insertOrderSQL = "INSERT INTO orders (customer_id, customer_name, lat, lon, demand, time_windows, service_duration, depot_id, quantities, required_skills, kind, priority, delivery_date, customer_synced_at, status)" +
+	" VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9, " + orderSkillsExpr + ", $11, $12, $13, CASE WHEN $1 <> 0 THEN CURRENT_TIMESTAMP END, 'pending') RETURNING id, customer_synced_at::text"
+
+// queryRower is satisfied by both the pool and a transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertOrder inserts o as a pending order, unpaired, and sets its ID. An
+// order with a CustomerID inherits the customer's master data (see inherit);
+// ErrCustomerNotFound is returned when there is no such customer, and
+// ErrCustomerInactive when it was deactivated.
+func insertOrder(ctx context.Context, q queryRower, o *Order) error {
+	if o.CustomerID != 0 {
+		var c Customer
+		err := scanCustomer(q.QueryRow(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = $1", o.CustomerID), &c)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrCustomerNotFound, o.CustomerID)
+		}
+		if err != nil {
+			return err
+		}
+		if c.Status == CustomerInactive {
+			return fmt.Errorf("%w: %d", ErrCustomerInactive, o.CustomerID)
+		}
+		o.inherit(c)
+	}
+	o.Quantities = withWeight(o.Quantities, &o.Demand)
+	o.RequiredSkills = normalizeSkills(o.RequiredSkills)
+	if o.Kind == "" {
+		o.Kind = OrderKindDelivery
+	}
+	if o.Priority == "" {
+		o.Priority = PriorityNormal
+	}
+	o.PairOrderID = nil
+	return q.QueryRow(ctx, insertOrderSQL,
+		o.CustomerID, o.CustomerName, o.Lat, o.Lon, o.Demand, o.TimeWindows, o.ServiceDuration, o.DepotID, o.Quantities.extra(), o.RequiredSkills, o.Kind, o.Priority, o.DeliveryDate).Scan(&o.ID, &o.CustomerSyncedAt)
 }
 
 func (r *Repository) CreateOrder(ctx context.Context, o *Order) error {
-	_, err := r.Pool.Exec(ctx, "INSERT INTO orders (customer_id, customer_name, lat, lon, demand, time_windows, service_duration, status) VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending')", o.CustomerID, o.CustomerName, o.Lat, o.Lon, o.Demand, o.TimeWindows, o.ServiceDuration)
-	return err
+	return insertOrder(ctx, r.Pool, o)
+}
+
+const orderColumns = "id, COALESCE(customer_id, 0), customer_name, lat, lon, demand, time_windows, service_duration, created_at::text, status, route_id, depot_id, quantities, required_skills, kind, pair_order_id, priority, delivery_date::text, customer_synced_at::text"
+
+func scanOrder(row pgx.Row, o *Order) error {
+	if err := row.Scan(&o.ID, &o.CustomerID, &o.CustomerName, &o.Lat, &o.Lon, &o.Demand, &o.TimeWindows, &o.ServiceDuration, &o.CreatedAt, &o.Status, &o.RouteID, &o.DepotID, &o.Quantities, &o.RequiredSkills, &o.Kind, &o.PairOrderID, &o.Priority, &o.DeliveryDate, &o.CustomerSyncedAt); err != nil {
+		return err
+	}
+	o.Quantities = withWeight(o.Quantities, &o.Demand)
+	return nil
 }
 
-func (r *Repository) ListOrders(ctx context.Context, status string, routeID int) ([]Order, error) {
-	query := "SELECT id, customer_id, customer_name, lat, lon, demand, time_windows, service_duration, created_at::text, status, route_id FROM orders WHERE 1=1"
+func scanOrders(rows pgx.Rows) ([]Order, error) {
+	defer rows.Close()
+	var orders []Order
+	for rows.Next() {
+		var o Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// ListOrders filters on whichever of status, routeID, depotID and date
+// (YYYY-MM-DD, the delivery date) are set.
+func (r *Repository) ListOrders(ctx context.Context, status string, routeID, depotID int, date string) ([]Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders WHERE 1=1"
 	args := []interface{}{}
 	argIdx := 1
 
@@ -146,22 +393,75 @@ func (r *Repository) ListOrders(ctx context.Context, status string, routeID int)
 		args = append(args, routeID)
 		argIdx++
 	}
+	if depotID != 0 {
+		query += fmt.Sprintf(" AND depot_id = $%d", argIdx)
+		args = append(args, depotID)
+		argIdx++
+	}
+	if date != "" {
+		query += fmt.Sprintf(" AND delivery_date = $%d", argIdx)
+		args = append(args, date)
+		argIdx++
+	}
 	query += " ORDER BY created_at DESC"
 
 	rows, err := r.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	return scanOrders(rows)
+}
+
+// ListStaleOrders returns the orders whose customer's master data changed
+// after they copied it.
+func (r *Repository) ListStaleOrders(ctx context.Context) ([]Order, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT "+orderColumns+" FROM orders WHERE customer_synced_at < (SELECT updated_at FROM customers WHERE id = orders.customer_id) ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	return scanOrders(rows)
+}
+
+func (r *Repository) ListOrdersByIDs(ctx context.Context, ids []int) ([]Order, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = ANY($1) ORDER BY id", ids)
+	if err != nil {
+		return nil, err
+	}
+	return scanOrders(rows)
+}
+
+// OrdersInOtherConfirmedRoutes maps each of the given orders that is routed
+// in a route other than routeID, confirmed or beyond (see IsCommitted), to
+// that route.
+func (r *Repository) OrdersInOtherConfirmedRoutes(ctx context.Context, ids []int, routeID int) (map[int]int, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT o.id, o.route_id FROM orders o JOIN routes rt ON rt.id = o.route_id WHERE o.id = ANY($1) AND rt.id <> $2 AND rt.status IN "+committedStatuses, ids, routeID)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
-	var orders []Order
+	found := make(map[int]int)
 	for rows.Next() {
-		var o Order
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.CustomerName, &o.Lat, &o.Lon, &o.Demand, &o.TimeWindows, &o.ServiceDuration, &o.CreatedAt, &o.Status, &o.RouteID); err != nil {
+		var orderID, otherRoute int
+		if err := rows.Scan(&orderID, &otherRoute); err != nil {
 			return nil, err
 		}
-		orders = append(orders, o)
+		found[orderID] = otherRoute
+	}
+	return found, rows.Err()
+}
+
+// ListOrdersForOptimization returns the orders an optimization run for date
+// (YYYY-MM-DD) works on: everything pending for that day or undated, plus
+// whatever sits in that day's draft route. With a depotID, only that depot's
+// draft and orders tied to it or to no depot.
+func (r *Repository) ListOrdersForOptimization(ctx context.Context, depotID int, date string) ([]Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders WHERE ((status = 'pending' AND (delivery_date IS NULL OR delivery_date = $2)) OR route_id IN (SELECT id FROM routes WHERE route_date = $2 AND status = 'draft' AND ($1 = 0 OR depot_id = $1)))"
+	query += " AND ($1 = 0 OR depot_id IS NULL OR depot_id = $1) ORDER BY id"
+	rows, err := r.Pool.Query(ctx, query, depotID, date)
+	if err != nil {
+		return nil, err
 	}
-	return orders, nil
+	return scanOrders(rows)
 }
 
 func (r *Repository) CreateOrdersBatch(ctx context.Context, orders []Order) error {
@@ -171,9 +471,8 @@ func (r *Repository) CreateOrdersBatch(ctx context.Context, orders []Order) erro
 	}
 	defer tx.Rollback(ctx)
 
-	for _, o := range orders {
-		_, err := tx.Exec(ctx, "INSERT INTO orders (customer_id, customer_name, lat, lon, demand, time_windows, service_duration, status) VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending')", o.CustomerID, o.CustomerName, o.Lat, o.Lon, o.Demand, o.TimeWindows, o.ServiceDuration)
-		if err != nil {
+	for i := range orders {
+		if err := insertOrder(ctx, tx, &orders[i]); err != nil {
 			return err
 		}
 	}
@@ -181,8 +480,23 @@ func (r *Repository) CreateOrdersBatch(ctx context.Context, orders []Order) erro
 	return tx.Commit(ctx)
 }
 
-func (r *Repository) ListRoutes(ctx context.Context) ([]Route, error) {
-	rows, err := r.Pool.Query(ctx, "SELECT id, solution_json, created_at::text, status FROM routes ORDER BY created_at DESC LIMIT 10")
+const routeColumns = "id, solution_json, created_at::text, status, depot_id, unassigned, route_date::text, stale, version"
+
+func scanRoute(row pgx.Row, rt *Route) error {
+	return row.Scan(&rt.ID, &rt.SolutionJSON, &rt.CreatedAt, &rt.Status, &rt.DepotID, &rt.Unassigned, &rt.RouteDate, &rt.Stale, &rt.Version)
+}
+
+// ListRoutes returns the latest routes, of depotID only when non-zero.
+func (r *Repository) ListRoutes(ctx context.Context, depotID int) ([]Route, error) {
+	query := "SELECT " + routeColumns + " FROM routes"
+	args := []interface{}{}
+	if depotID != 0 {
+		query += " WHERE depot_id = $1"
+		args = append(args, depotID)
+	}
+	query += " ORDER BY created_at DESC LIMIT 10"
+
+	rows, err := r.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -190,28 +504,69 @@ func (r *Repository) ListRoutes(ctx context.Context) ([]Route, error) {
 	var routes []Route
 	for rows.Next() {
 		var rt Route
-		if err := rows.Scan(&rt.ID, &rt.SolutionJSON, &rt.CreatedAt, &rt.Status); err != nil {
+		if err := scanRoute(rows, &rt); err != nil {
 			return nil, err
 		}
 		routes = append(routes, rt)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	listed := make([]*Route, len(routes))
+	for i := range routes {
+		listed[i] = &routes[i]
+	}
+	if err := r.attachDrivers(ctx, listed); err != nil {
+		return nil, err
+	}
 	return routes, nil
 }
 
-func (r *Repository) CreateRoute(ctx context.Context, rt *Route) error {
-	var id int
-	err := r.Pool.QueryRow(ctx, "INSERT INTO routes (solution_json, status) VALUES ($1, $2) RETURNING id", rt.SolutionJSON, rt.Status).Scan(&id)
+// CreateRoute saves the route with its first revision, by author.
+func (r *Repository) CreateRoute(ctx context.Context, rt *Route, author string) error {
+	tx, err := r.Pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	rt.ID = id
-	return nil
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, "INSERT INTO routes (solution_json, status, depot_id, route_date) VALUES ($1, $2, $3, COALESCE(NULLIF($4, '')::date, CURRENT_DATE)) RETURNING id, route_date::text, version",
+		rt.SolutionJSON.withoutDrivers(), rt.Status, rt.DepotID, rt.RouteDate).Scan(&rt.ID, &rt.RouteDate, &rt.Version)
+	if err != nil {
+		return err
+	}
+	if err := insertRevision(ctx, tx, rt.ID, rt.SolutionJSON, rt.Unassigned, RevisionManual, author); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *Repository) UpdateRoute(ctx context.Context, rt *Route) error {
-	// Simple update for now, status and solution
-	_, err := r.Pool.Exec(ctx, "UPDATE routes SET solution_json = $1, status = $2 WHERE id = $3", rt.SolutionJSON, rt.Status, rt.ID)
-	return err
+// UpdateRoute saves the route's solution as a new revision from source by
+// author, provided the route is still at rt.Version, and sets the new version.
+// Otherwise it returns a *VersionConflictError, or pgx.ErrNoRows when the
+// route is gone.
+func (r *Repository) UpdateRoute(ctx context.Context, rt *Route, source, author string) error {
+	// Solution only: the status changes through TransitionRoute
+	rt.Unassigned = stillUnassigned(rt.Unassigned, rt.SolutionJSON)
+	rt.Stale = false
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, "UPDATE routes SET solution_json = $1, unassigned = $2, stale = FALSE WHERE id = $3 AND version = $4 RETURNING version",
+		rt.SolutionJSON.withoutDrivers(), rt.Unassigned, rt.ID, rt.Version).Scan(&rt.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.versionConflict(ctx, rt.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertRevision(ctx, tx, rt.ID, rt.SolutionJSON, rt.Unassigned, source, author); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // Add method to update Orders batch
@@ -248,9 +603,95 @@ func (r *Repository) RecruitOrdersToRoute(ctx context.Context, routeID int, orde
 
 func (r *Repository) GetRoute(ctx context.Context, id int) (*Route, error) {
 	var rt Route
-	err := r.Pool.QueryRow(ctx, "SELECT id, solution_json, created_at::text, status FROM routes WHERE id = $1", id).Scan(&rt.ID, &rt.SolutionJSON, &rt.CreatedAt, &rt.Status)
+	err := scanRoute(r.Pool.QueryRow(ctx, "SELECT "+routeColumns+" FROM routes WHERE id = $1", id), &rt)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.AttachDrivers(ctx, &rt); err != nil {
+		return nil, err
+	}
 	return &rt, nil
 }
+
+// versionConflict reports the current version of a route a conditional
+// update missed, or pgx.ErrNoRows when there is no such route.
+func (r *Repository) versionConflict(ctx context.Context, id int) error {
+	var current int
+	if err := r.Pool.QueryRow(ctx, "SELECT version FROM routes WHERE id = $1", id).Scan(&current); err != nil {
+		return err
+	}
+	return &VersionConflictError{RouteID: id, Current: current}
+}
+
+// DraftVersion identifies the draft route of a depot and day as read before
+// optimizing; the zero value means there was none.
+type DraftVersion struct {
+	RouteID int
+	Version int
+}
+
+const findDraftSQL = "SELECT id, version FROM routes WHERE route_date = $2 AND status = 'draft' AND depot_id IS NOT DISTINCT FROM NULLIF($1, 0) LIMIT 1"
+
+// GetDraftVersion returns the draft route of the depot (0 for none) on date.
+func (r *Repository) GetDraftVersion(ctx context.Context, depotID int, date string) (DraftVersion, error) {
+	var seen DraftVersion
+	err := r.Pool.QueryRow(ctx, findDraftSQL, depotID, date).Scan(&seen.RouteID, &seen.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DraftVersion{}, nil
+	}
+	return seen, err
+}
+
+// SaveDraftSolution upserts the draft route of the depot (0 for none) on date
+// (YYYY-MM-DD) with the given solution and the orders left out, records it as
+// a solver revision, and assigns its orders to it, the same way solver.py does.
+// The draft must still be as seen before optimizing: a draft edited, or one
+// created, in the meantime is a *VersionConflictError.
+func (r *Repository) SaveDraftSolution(ctx context.Context, solution Solution, unassigned []UnassignedOrder, depotID int, date string, seen DraftVersion) (int, error) {
+	if unassigned == nil {
+		unassigned = []UnassignedOrder{}
+	}
+	orderIDs := solution.OrderIDs()
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	routeID := seen.RouteID
+	if routeID == 0 {
+		var current DraftVersion
+		err = tx.QueryRow(ctx, findDraftSQL, depotID, date).Scan(&current.RouteID, &current.Version)
+		if err == nil {
+			return 0, &VersionConflictError{RouteID: current.RouteID, Current: current.Version}
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return 0, err
+		}
+		err = tx.QueryRow(ctx, "INSERT INTO routes (solution_json, route_date, status, depot_id, unassigned) VALUES ($1, $4, 'draft', NULLIF($2, 0), $3) RETURNING id", solution, depotID, unassigned, date).Scan(&routeID)
+	} else {
+		var updated int
+		err = tx.QueryRow(ctx, "UPDATE routes SET solution_json = $1, unassigned = $2, stale = FALSE, created_at = CURRENT_TIMESTAMP WHERE id = $3 AND version = $4 AND status = 'draft' RETURNING id",
+			solution, unassigned, routeID, seen.Version).Scan(&updated)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, r.versionConflict(ctx, routeID)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := insertRevision(ctx, tx, routeID, solution, unassigned, RevisionSolver, SolverAuthor); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE orders SET status = 'pending', route_id = NULL WHERE route_id = $1", routeID); err != nil {
+		return 0, err
+	}
+	if len(orderIDs) > 0 {
+		if _, err := tx.Exec(ctx, "UPDATE orders SET status = 'routed', route_id = $1 WHERE id = ANY($2)", routeID, orderIDs); err != nil {
+			return 0, err
+		}
+	}
+
+	return routeID, tx.Commit(ctx)
+}
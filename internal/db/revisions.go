@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Revision sources: who saved a route's solution.
+const (
+	RevisionSolver   = "solver"
+	RevisionManual   = "manual"
+	RevisionRollback = "rollback"
+)
+
+// SolverAuthor is the author of the revisions the optimizer saves, here and
+// in optimization/solver.py.
+const SolverAuthor = "optimizer"
+
+// RouteRevision is an immutable copy of a route's solution as saved.
+// Revisions are numbered from 1 per route. Listings leave the solution out.
+type RouteRevision struct {
+	ID           int               `json:"id"`
+	RouteID      int               `json:"route_id"`
+	Revision     int               `json:"revision"`
+	Source       string            `json:"source"`
+	Author       string            `json:"author"`
+	CreatedAt    string            `json:"created_at"`
+	SolutionJSON *Solution         `json:"solution_json,omitempty"`
+	Unassigned   []UnassignedOrder `json:"unassigned,omitempty"`
+}
+
+// insertRevisionSQL numbers the revision after the route's latest one. Callers
+// hold the route row locked, having just written it.
+const insertRevisionSQL = "INSERT INTO route_revisions (route_id, revision, solution_json, unassigned, source, author)" +
+	" SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5 FROM route_revisions WHERE route_id = $1"
+
+func insertRevision(ctx context.Context, tx pgx.Tx, routeID int, sol Solution, unassigned []UnassignedOrder, source, author string) error {
+	if unassigned == nil {
+		unassigned = []UnassignedOrder{}
+	}
+	_, err := tx.Exec(ctx, insertRevisionSQL, routeID, sol.withoutDrivers(), unassigned, source, author)
+	return err
+}
+
+// ListRouteRevisions returns the route's revisions, newest first, without
+// their solutions.
+func (r *Repository) ListRouteRevisions(ctx context.Context, routeID int) ([]RouteRevision, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT id, route_id, revision, source, author, created_at::text FROM route_revisions WHERE route_id = $1 ORDER BY revision DESC", routeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := []RouteRevision{}
+	for rows.Next() {
+		var rev RouteRevision
+		if err := rows.Scan(&rev.ID, &rev.RouteID, &rev.Revision, &rev.Source, &rev.Author, &rev.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// GetRouteRevision returns pgx.ErrNoRows when the route has no such revision.
+func (r *Repository) GetRouteRevision(ctx context.Context, routeID, revision int) (*RouteRevision, error) {
+	rev := RouteRevision{SolutionJSON: &Solution{}}
+	err := r.Pool.QueryRow(ctx, "SELECT id, route_id, revision, source, author, created_at::text, solution_json, unassigned FROM route_revisions WHERE route_id = $1 AND revision = $2", routeID, revision).
+		Scan(&rev.ID, &rev.RouteID, &rev.Revision, &rev.Source, &rev.Author, &rev.CreatedAt, rev.SolutionJSON, &rev.Unassigned)
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// StopPosition is where an order is served: the vehicle and the 0-based
+// position among the vehicle's order stops.
+type StopPosition struct {
+	VehicleDBID int `json:"vehicle_db_id"`
+	Position    int `json:"position"`
+}
+
+// StopChange is an order whose stop differs between two revisions. From is
+// nil for added stops and To for removed ones.
+type StopChange struct {
+	OrderID int           `json:"order_id"`
+	From    *StopPosition `json:"from,omitempty"`
+	To      *StopPosition `json:"to,omitempty"`
+}
+
+// RevisionDiff lists the stops added, removed and moved to another vehicle or
+// position going from one revision to another.
+type RevisionDiff struct {
+	From    int          `json:"from"`
+	To      int          `json:"to"`
+	Added   []StopChange `json:"added"`
+	Removed []StopChange `json:"removed"`
+	Moved   []StopChange `json:"moved"`
+}
+
+func stopPositions(sol Solution) map[int]StopPosition {
+	positions := make(map[int]StopPosition)
+	for _, v := range sol.Vehicles {
+		for i, id := range v.OrderIDs() {
+			positions[id] = StopPosition{VehicleDBID: v.VehicleDBID, Position: i}
+		}
+	}
+	return positions
+}
+
+// DiffSolutions compares the stops of two solutions, in the order they are
+// visited in to (added and moved) and in from (removed).
+func DiffSolutions(from, to Solution) RevisionDiff {
+	diff := RevisionDiff{Added: []StopChange{}, Removed: []StopChange{}, Moved: []StopChange{}}
+	before, after := stopPositions(from), stopPositions(to)
+	for _, id := range to.OrderIDs() {
+		now := after[id]
+		was, ok := before[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, StopChange{OrderID: id, To: &now})
+		case was != now:
+			diff.Moved = append(diff.Moved, StopChange{OrderID: id, From: &was, To: &now})
+		}
+	}
+	for _, id := range from.OrderIDs() {
+		if _, ok := after[id]; !ok {
+			was := before[id]
+			diff.Removed = append(diff.Removed, StopChange{OrderID: id, From: &was})
+		}
+	}
+	return diff
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Route statuses, in lifecycle order. Completed and cancelled routes are
+// final.
+const (
+	RouteDraft      = "draft"
+	RouteConfirmed  = "confirmed"
+	RouteDispatched = "dispatched"
+	RouteInProgress = "in_progress"
+	RouteCompleted  = "completed"
+	RouteCancelled  = "cancelled"
+)
+
+// routeTransitions lists the statuses each status may move to.
+var routeTransitions = map[string][]string{
+	RouteDraft:      {RouteConfirmed, RouteCancelled},
+	RouteConfirmed:  {RouteDispatched, RouteCancelled},
+	RouteDispatched: {RouteInProgress, RouteCancelled},
+	RouteInProgress: {RouteCompleted, RouteCancelled},
+}
+
+// CanTransition reports whether a route may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, s := range routeTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCommitted reports whether a route in status holds its orders for good:
+// confirmed and beyond, but not cancelled.
+func IsCommitted(status string) bool {
+	switch status {
+	case RouteConfirmed, RouteDispatched, RouteInProgress, RouteCompleted:
+		return true
+	}
+	return false
+}
+
+// committedStatuses is IsCommitted as an SQL list.
+const committedStatuses = "('confirmed', 'dispatched', 'in_progress', 'completed')"
+
+// ErrIllegalTransition is returned when a route cannot move to the requested
+// status from its current one.
+var ErrIllegalTransition = errors.New("illegal route status transition")
+
+// RouteStatusChange records who moved a route between two statuses, and when.
+type RouteStatusChange struct {
+	ID         int    `json:"id"`
+	RouteID    int    `json:"route_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	ChangedBy  string `json:"changed_by"`
+	ChangedAt  string `json:"changed_at"`
+}
+
+// TransitionRoute moves the route to status to on behalf of user and records
+// the change, provided the route is still at version. Cancelling a route
+// releases its orders back to pending. It returns a *VersionConflictError when
+// the route changed meanwhile and pgx.ErrNoRows when it does not exist.
+func (r *Repository) TransitionRoute(ctx context.Context, id, version int, to, user string) (*RouteStatusChange, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ch := RouteStatusChange{RouteID: id, ToStatus: to, ChangedBy: user}
+	var current int
+	if err := tx.QueryRow(ctx, "SELECT status, version FROM routes WHERE id = $1 FOR UPDATE", id).Scan(&ch.FromStatus, &current); err != nil {
+		return nil, err
+	}
+	if current != version {
+		return nil, &VersionConflictError{RouteID: id, Current: current}
+	}
+	if !CanTransition(ch.FromStatus, to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrIllegalTransition, ch.FromStatus, to)
+	}
+	err = tx.QueryRow(ctx, "UPDATE routes SET status = $1 WHERE id = $2 AND version = $3 RETURNING version", to, id, version).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.versionConflict(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if to == RouteCancelled {
+		if _, err := tx.Exec(ctx, "UPDATE orders SET status = 'pending', route_id = NULL WHERE route_id = $1", id); err != nil {
+			return nil, err
+		}
+	}
+	err = tx.QueryRow(ctx, "INSERT INTO route_status_changes (route_id, from_status, to_status, changed_by) VALUES ($1, $2, $3, $4) RETURNING id, changed_at::text",
+		id, ch.FromStatus, to, user).Scan(&ch.ID, &ch.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ch, tx.Commit(ctx)
+}
+
+// ListRouteStatusChanges returns the route's status history, oldest first.
+func (r *Repository) ListRouteStatusChanges(ctx context.Context, routeID int) ([]RouteStatusChange, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT id, route_id, from_status, to_status, changed_by, changed_at::text FROM route_status_changes WHERE route_id = $1 ORDER BY changed_at, id", routeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	changes := []RouteStatusChange{}
+	for rows.Next() {
+		var ch RouteStatusChange
+		if err := rows.Scan(&ch.ID, &ch.RouteID, &ch.FromStatus, &ch.ToStatus, &ch.ChangedBy, &ch.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+	return changes, rows.Err()
+}
@@ -56,3 +56,279 @@ ALTER TABLE orders ADD COLUMN IF NOT EXISTS route_id INT REFERENCES routes(id);
 --     END IF;
 -- END $$;
 
+
+-- Distance/time matrix cache, one row per planning date and directed coordinate pair.
+-- Keys are "lat,lon" rounded to 6 decimals (see matrix.Point.Key)
+CREATE TABLE IF NOT EXISTS matrix_cache (
+    provider TEXT NOT NULL,
+    plan_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    from_key TEXT NOT NULL,
+    to_key TEXT NOT NULL,
+    distance_m INT NOT NULL, -- Meters
+    duration_min INT NOT NULL, -- Minutes
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, plan_date, from_key, to_key)
+);
+
+-- Older caches were keyed without the planning date
+ALTER TABLE matrix_cache ADD COLUMN IF NOT EXISTS plan_date DATE NOT NULL DEFAULT CURRENT_DATE;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+                   WHERE i.indrelid = 'matrix_cache'::regclass AND i.indisprimary AND a.attname = 'plan_date') THEN
+        ALTER TABLE matrix_cache DROP CONSTRAINT matrix_cache_pkey;
+        ALTER TABLE matrix_cache ADD PRIMARY KEY (provider, plan_date, from_key, to_key);
+    END IF;
+END $$;
+
+-- Optimization jobs, so clients can poll for the outcome of a solve
+CREATE TABLE IF NOT EXISTS optimization_runs (
+    id SERIAL PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'queued', -- queued, running, succeeded, failed
+    requested_route_id INT REFERENCES routes(id), -- Route asked to be reprocessed, if any
+    route_id INT REFERENCES routes(id), -- Route written by the solver
+    objective_value BIGINT,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+-- solution_json schema version 1 (see db.Solution).
+-- Older planner saves used "vehicle_id" instead of "vehicle_db_id"; rename it so
+-- strict decoding accepts those rows, then stamp the version on unversioned rows.
+UPDATE routes SET solution_json = jsonb_set(solution_json, '{vehicles}', (
+    SELECT COALESCE(jsonb_agg(
+        CASE WHEN v ? 'vehicle_id' THEN (v - 'vehicle_id') || jsonb_build_object('vehicle_db_id', v->'vehicle_id') ELSE v END
+        ORDER BY idx), '[]'::jsonb)
+    FROM jsonb_array_elements(solution_json->'vehicles') WITH ORDINALITY AS e(v, idx)
+))
+WHERE jsonb_typeof(solution_json->'vehicles') = 'array'
+  AND solution_json @? '$.vehicles[*] ? (exists(@.vehicle_id))';
+
+UPDATE routes SET solution_json = solution_json || '{"schema_version": 1}'::jsonb
+WHERE NOT solution_json ? 'schema_version';
+
+-- Depots (warehouses). Opening hours in minutes from midnight
+CREATE TABLE IF NOT EXISTS depots (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    lat FLOAT NOT NULL,
+    lon FLOAT NOT NULL,
+    opens_at INT NOT NULL DEFAULT 0,
+    closes_at INT NOT NULL DEFAULT 1440
+);
+
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS depot_id INT REFERENCES depots(id); -- Home depot
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS depot_id INT REFERENCES depots(id); -- Fulfilling depot, NULL = any
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS depot_id INT REFERENCES depots(id);
+ALTER TABLE optimization_runs ADD COLUMN IF NOT EXISTS depot_id INT REFERENCES depots(id);
+
+-- Vehicle end locations. NULL ends where the vehicle started; open routes
+-- finish at the last stop and have no way back
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS end_lat FLOAT;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS end_lon FLOAT;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS route_mode TEXT NOT NULL DEFAULT 'return';
+
+-- optimization/migrate_vehicles.py used to create these with a 0.0 default
+ALTER TABLE vehicles ALTER COLUMN end_lat DROP DEFAULT;
+ALTER TABLE vehicles ALTER COLUMN end_lon DROP DEFAULT;
+UPDATE vehicles SET end_lat = NULL, end_lon = NULL WHERE end_lat = 0 AND end_lon = 0;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'vehicles_route_mode_check') THEN
+        ALTER TABLE vehicles ADD CONSTRAINT vehicles_route_mode_check CHECK (route_mode IN ('return', 'open'));
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'vehicles_end_location_check') THEN
+        ALTER TABLE vehicles ADD CONSTRAINT vehicles_end_location_check CHECK ((end_lat IS NULL) = (end_lon IS NULL));
+    END IF;
+END $$;
+
+-- Vehicle calendars. Weekly shifts (weekday 0 = Sunday) in minutes from
+-- midnight; a vehicle without shifts works every day. Exceptions override a
+-- single date: a day off, or different hours (NULL = the weekday's shift)
+CREATE TABLE IF NOT EXISTS vehicle_shifts (
+    vehicle_id INT NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    weekday INT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    starts_at INT NOT NULL,
+    ends_at INT NOT NULL,
+    PRIMARY KEY (vehicle_id, weekday)
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_availability_exceptions (
+    vehicle_id INT NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    available BOOLEAN NOT NULL,
+    starts_at INT,
+    ends_at INT,
+    reason TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (vehicle_id, date)
+);
+
+-- Drivers and who drives which vehicle each day. License categories follow
+-- the CNH (A, B to E, optionally combined with A as in "AD")
+CREATE TABLE IF NOT EXISTS drivers (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    license_category TEXT NOT NULL,
+    phone TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'inactive', 'on_leave'))
+);
+
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS required_license TEXT NOT NULL DEFAULT ''; -- '' = any license
+
+CREATE TABLE IF NOT EXISTS driver_assignments (
+    date DATE NOT NULL,
+    vehicle_id INT NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    driver_id INT NOT NULL REFERENCES drivers(id),
+    PRIMARY KEY (date, vehicle_id),
+    UNIQUE (date, driver_id)
+);
+
+-- Capacity dimensions. "weight" is the legacy capacity/demand columns; the
+-- others live in the capacities/quantities JSONB maps ({"volume": 12})
+CREATE TABLE IF NOT EXISTS capacity_dimensions (
+    name TEXT PRIMARY KEY,
+    unit TEXT NOT NULL
+);
+
+INSERT INTO capacity_dimensions (name, unit) VALUES ('weight', 'kg') ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS capacities JSONB NOT NULL DEFAULT '{}'::JSONB;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS quantities JSONB NOT NULL DEFAULT '{}'::JSONB;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS quantities JSONB NOT NULL DEFAULT '{}'::JSONB;
+
+-- Skills (e.g. refrigerated, tail_lift): what a vehicle offers and what an
+-- order, or by default its customer, requires
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS skills TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS required_skills TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS required_skills TEXT[] NOT NULL DEFAULT '{}';
+
+-- Orders an optimization run left out, with the reason: [{"order_id": 1, "reason": "..."}]
+ALTER TABLE optimization_runs ADD COLUMN IF NOT EXISTS unassigned JSONB NOT NULL DEFAULT '[]'::JSONB;
+
+-- Pickup-and-delivery pairs. A plain delivery is loaded at the depot; a
+-- pickup is loaded at its own location and either brought back to the depot
+-- (a return) or, when paired, dropped at its delivery. Both orders of a pair
+-- point at each other through pair_order_id
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'delivery' CHECK (kind IN ('delivery', 'pickup'));
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pair_order_id INT REFERENCES orders(id);
+
+-- Order priorities and what the optimizer pays for leaving one out. One row
+-- per priority; the deployment is the tenant
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'normal' CHECK (priority IN ('mandatory', 'high', 'normal', 'low'));
+
+CREATE TABLE IF NOT EXISTS drop_penalties (
+    priority TEXT PRIMARY KEY CHECK (priority IN ('mandatory', 'high', 'normal', 'low')),
+    penalty BIGINT NOT NULL CHECK (penalty > 0)
+);
+
+INSERT INTO drop_penalties (priority, penalty) VALUES
+    ('mandatory', 100000000), ('high', 5000000), ('normal', 1000000), ('low', 200000)
+ON CONFLICT (priority) DO NOTHING;
+
+-- Orders the last optimization left out of the route: [{"order_id": 1, "reason": "...", "priority": "mandatory"}]
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS unassigned JSONB NOT NULL DEFAULT '[]'::JSONB;
+
+-- Delivery dates. Orders without one can go out on any day; optimization
+-- runs plan a single date, today by default
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_date DATE;
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_date ON orders (delivery_date);
+ALTER TABLE optimization_runs ADD COLUMN IF NOT EXISTS plan_date DATE NOT NULL DEFAULT CURRENT_DATE;
+
+-- Orders reference the customer master. References to customers that do not
+-- exist (including the 0 that used to mean "no customer") are cleared first
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'orders_customer_id_fkey') THEN
+        UPDATE orders SET customer_id = NULL WHERE customer_id NOT IN (SELECT id FROM customers);
+        ALTER TABLE orders ADD CONSTRAINT orders_customer_id_fkey FOREIGN KEY (customer_id) REFERENCES customers(id);
+    END IF;
+END $$;
+
+-- When customer master data last changed, and when an order copied it: an
+-- order synced before its customer changed is stale
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_synced_at TIMESTAMP;
+
+CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS customers_touch_updated_at ON customers;
+CREATE TRIGGER customers_touch_updated_at BEFORE UPDATE ON customers
+    FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+-- Customers with orders cannot be deleted; deactivating one keeps its order
+-- history but refuses new orders
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'inactive'));
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
+
+-- Set when an order of a draft route is edited or deleted after planning,
+-- cleared whenever the route's solution is saved again
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS stale BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Retired vehicles are kept for the routes that used them
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Route lifecycle: draft -> confirmed -> dispatched -> in_progress ->
+-- completed, or cancelled from any of the first four. Every change is
+-- recorded with who made it. Statuses written before the check are folded
+-- in first: case and spacing variants keep their meaning, anything unknown
+-- goes back to draft
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'routes_status_check') THEN
+        UPDATE routes SET status = CASE
+                WHEN lower(trim(status)) IN ('draft', 'confirmed', 'dispatched', 'in_progress', 'completed', 'cancelled')
+                    THEN lower(trim(status))
+                WHEN lower(trim(status)) IN ('in progress', 'in-progress') THEN 'in_progress'
+                WHEN lower(trim(status)) = 'canceled' THEN 'cancelled'
+                ELSE 'draft'
+            END
+        WHERE status NOT IN ('draft', 'confirmed', 'dispatched', 'in_progress', 'completed', 'cancelled');
+        ALTER TABLE routes ADD CONSTRAINT routes_status_check
+            CHECK (status IN ('draft', 'confirmed', 'dispatched', 'in_progress', 'completed', 'cancelled'));
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS route_status_changes (
+    id SERIAL PRIMARY KEY,
+    route_id INT NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    changed_by TEXT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_route_status_changes_route_id ON route_status_changes(route_id);
+
+-- Every saved solution of a route, numbered per route. Source is solver,
+-- manual or rollback; author is the X-User of manual saves and rollbacks
+CREATE TABLE IF NOT EXISTS route_revisions (
+    id SERIAL PRIMARY KEY,
+    route_id INT NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    solution_json JSONB NOT NULL,
+    unassigned JSONB NOT NULL DEFAULT '[]'::JSONB,
+    source TEXT NOT NULL CHECK (source IN ('solver', 'manual', 'rollback')),
+    author TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (route_id, revision)
+);
+
+-- Optimistic concurrency: every write to a route bumps its version, which the
+-- API serves as the ETag. Writers update WHERE version = the one they read
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_version() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS routes_bump_version ON routes;
+CREATE TRIGGER routes_bump_version BEFORE UPDATE ON routes
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
@@ -0,0 +1,36 @@
+package db
+
+import "context"
+
+// Shipment is a pickup-and-delivery pair: goods collected at Pickup and
+// dropped at Delivery by the same vehicle, in that order.
+type Shipment struct {
+	Pickup   Order `json:"pickup"`
+	Delivery Order `json:"delivery"`
+}
+
+// CreateShipment inserts both orders of s and links them to each other. The
+// delivery drops exactly what the pickup loads, so it takes its quantities.
+func (r *Repository) CreateShipment(ctx context.Context, s *Shipment) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	s.Pickup.Kind = OrderKindPickup
+	s.Delivery.Kind = OrderKindDelivery
+	if err := insertOrder(ctx, tx, &s.Pickup); err != nil {
+		return err
+	}
+	s.Delivery.Demand, s.Delivery.Quantities = s.Pickup.Demand, s.Pickup.Quantities
+	if err := insertOrder(ctx, tx, &s.Delivery); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE orders SET pair_order_id = CASE id WHEN $1 THEN $2 ELSE $1 END WHERE id IN ($1, $2)", s.Pickup.ID, s.Delivery.ID)
+	if err != nil {
+		return err
+	}
+	s.Pickup.PairOrderID, s.Delivery.PairOrderID = &s.Delivery.ID, &s.Pickup.ID
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"sort"
+	"strings"
+)
+
+// normalizeSkills lowercases, trims and deduplicates skill tags such as
+// "refrigerated" or "tail_lift", so matching does not depend on spelling.
+func normalizeSkills(skills []string) []string {
+	seen := make(map[string]bool, len(skills))
+	out := []string{}
+	for _, s := range skills {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// SolutionSchemaVersion is the layout of routes.solution_json written by this
+// code and by optimization/solver.py. Bump it together with a migration in schema.sql.
+const SolutionSchemaVersion = 1
+
+// StopTypeEnd marks the last stop of a vehicle route (its end depot).
+const StopTypeEnd = "end"
+
+type Solution struct {
+	SchemaVersion int            `json:"schema_version"`
+	Vehicles      []VehicleRoute `json:"vehicles"`
+}
+
+type VehicleRoute struct {
+	VehicleDBID    int    `json:"vehicle_db_id"`
+	Route          []Stop `json:"route"`
+	TotalDistanceM int    `json:"total_distance_m"`
+	// Driver is filled in when reading confirmed routes and never stored
+	Driver *Driver `json:"driver,omitempty"`
+}
+
+// Stop is a visit in a vehicle route. The first stop is the vehicle's start
+// depot and the last one, with Type "end", its end depot; everything in
+// between carries an order.
+type Stop struct {
+	NodeIndex    int    `json:"node_index"`
+	MinTime      int    `json:"min_time"`
+	MaxTime      int    `json:"max_time"`
+	OrderID      int    `json:"order_id,omitempty"`
+	CustomerID   int    `json:"customer_id,omitempty"`
+	CustomerName string `json:"customer_name,omitempty"`
+	Type         string `json:"type,omitempty"`
+	// Minutes spent waiting for the window to open / arriving after it closed
+	WaitTime int `json:"wait_time,omitempty"`
+	Lateness int `json:"lateness,omitempty"`
+}
+
+func (s Stop) IsEnd() bool {
+	return s.Type == StopTypeEnd
+}
+
+func (s Stop) IsOrder() bool {
+	return s.OrderID != 0
+}
+
+// OrderIDs returns the order IDs of the vehicle's stops, in visiting order.
+func (v VehicleRoute) OrderIDs() []int {
+	var ids []int
+	for _, stop := range v.Route {
+		if stop.IsOrder() {
+			ids = append(ids, stop.OrderID)
+		}
+	}
+	return ids
+}
+
+// OrderIDs returns every order served by the solution.
+func (s Solution) OrderIDs() []int {
+	var ids []int
+	for _, v := range s.Vehicles {
+		ids = append(ids, v.OrderIDs()...)
+	}
+	return ids
+}
+
+// WithoutOrders drops the stops of the given orders. Timings of the remaining
+// stops are left as they were.
+func (s Solution) WithoutOrders(ids map[int]bool) Solution {
+	vehicles := make([]VehicleRoute, len(s.Vehicles))
+	for i, v := range s.Vehicles {
+		route := make([]Stop, 0, len(v.Route))
+		for _, stop := range v.Route {
+			if !ids[stop.OrderID] {
+				route = append(route, stop)
+			}
+		}
+		v.Route = route
+		vehicles[i] = v
+	}
+	s.Vehicles = vehicles
+	return s
+}
+
+// withoutDrivers is the solution as stored in routes.solution_json.
+func (s Solution) withoutDrivers() Solution {
+	vehicles := make([]VehicleRoute, len(s.Vehicles))
+	for i, v := range s.Vehicles {
+		v.Driver = nil
+		vehicles[i] = v
+	}
+	s.Vehicles = vehicles
+	return s
+}
+
+// UnmarshalJSON decodes strictly: unknown fields anywhere in the document are
+// rejected, and so are versions newer than SolutionSchemaVersion. Documents
+// without a version predate it and are read as version 1.
+func (s *Solution) UnmarshalJSON(data []byte) error {
+	type plain Solution
+	var p plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return fmt.Errorf("invalid solution: %w", err)
+	}
+	if p.SchemaVersion == 0 {
+		p.SchemaVersion = 1
+	}
+	if p.SchemaVersion > SolutionSchemaVersion {
+		return fmt.Errorf("unsupported solution schema_version %d (max %d)", p.SchemaVersion, SolutionSchemaVersion)
+	}
+	if p.Vehicles == nil {
+		p.Vehicles = []VehicleRoute{}
+	}
+	*s = Solution(p)
+	return nil
+}
+
+// MarshalJSON always writes the current schema version.
+func (s Solution) MarshalJSON() ([]byte, error) {
+	type plain Solution
+	p := plain(s)
+	p.SchemaVersion = SolutionSchemaVersion
+	if p.Vehicles == nil {
+		p.Vehicles = []VehicleRoute{}
+	}
+	return json.Marshal(p)
+}
@@ -0,0 +1,166 @@
+package kpi
+
+import (
+	"route-go/internal/solver"
+)
+
+// Metrics are in meters and minutes. Times assume the vehicle leaves the
+// depot as late as the route allows, so waiting only counts real idle time.
+// Load and Capacity are the weight dimension, Load being the most carried at
+// once; MaxUtilization looks at every dimension the vehicle is limited in.
+type Metrics struct {
+	DistanceM      int     `json:"distance_m"`
+	DrivingTimeMin int     `json:"driving_time_min"`
+	ServiceTimeMin int     `json:"service_time_min"`
+	WaitingTimeMin int     `json:"waiting_time_min"`
+	DurationMin    int     `json:"duration_min"`
+	Load           int     `json:"load"`
+	Capacity       int     `json:"capacity"`
+	Utilization    float64 `json:"utilization"` // Load / Capacity
+	MaxUtilization float64 `json:"max_utilization"`
+	Stops          int     `json:"stops"`
+	OnTimeStops    int     `json:"on_time_stops"`
+	LateStops      int     `json:"late_stops"`
+	LatenessMin    int     `json:"lateness_min"`
+}
+
+type VehicleKPI struct {
+	VehicleDBID int `json:"vehicle_db_id"`
+	Metrics
+}
+
+type Report struct {
+	Vehicles []VehicleKPI `json:"vehicles"`
+	Total    Metrics      `json:"total"`
+}
+
+// Compute measures every known vehicle route of the plan. The plan's
+// problem needs its matrix loaded.
+func Compute(plan *solver.Plan) Report {
+	p := plan.Problem
+	report := Report{Vehicles: []VehicleKPI{}}
+	loads := make([]int, len(p.Dimensions))
+	capacities := make([]int, len(p.Dimensions))
+	for _, pr := range plan.Routes {
+		if pr.Vehicle < 0 {
+			continue
+		}
+		m := measure(p, pr.Vehicle, pr.Seq)
+		report.Vehicles = append(report.Vehicles, VehicleKPI{VehicleDBID: pr.VehicleDBID, Metrics: m})
+		report.Total.add(m)
+
+		for d, load := range p.PeakLoad(pr.Seq) {
+			loads[d] += load
+			capacities[d] = min(capacities[d]+p.Vehicles[pr.Vehicle].Capacity[d], solver.Unlimited)
+		}
+	}
+	report.Total.Utilization = utilization(report.Total.Load, report.Total.Capacity)
+	report.Total.MaxUtilization = maxUtilization(loads, capacities)
+	return report
+}
+
+func measure(p *solver.Problem, v int, seq []int) Metrics {
+	load := p.PeakLoad(seq)
+	capacity := p.Vehicles[v].Capacity
+	m := Metrics{
+		DistanceM:      p.Distance(v, seq),
+		Load:           load[0],
+		Capacity:       capacity[0],
+		Stops:          len(seq),
+		MaxUtilization: maxUtilization(load, capacity),
+	}
+	m.Utilization = utilization(m.Load, m.Capacity)
+	if len(seq) == 0 {
+		return m
+	}
+
+	latest := p.Timeline(v, seq)[0].MaxTime
+	visits := p.Simulate(v, seq, latest)
+	for i, visit := range visits {
+		if i > 0 {
+			m.DrivingTimeMin += p.Time[visits[i-1].Node][visit.Node]
+		}
+		m.ServiceTimeMin += p.Nodes[visit.Node].Service
+		m.WaitingTimeMin += visit.Wait
+		if visit.Node >= p.NumOrders {
+			continue
+		}
+		if visit.Late > 0 {
+			m.LateStops++
+			m.LatenessMin += visit.Late
+		} else {
+			m.OnTimeStops++
+		}
+	}
+	m.DurationMin = visits[len(visits)-1].MinTime - visits[0].MinTime
+	return m
+}
+
+func (t *Metrics) add(m Metrics) {
+	t.DistanceM += m.DistanceM
+	t.DrivingTimeMin += m.DrivingTimeMin
+	t.ServiceTimeMin += m.ServiceTimeMin
+	t.WaitingTimeMin += m.WaitingTimeMin
+	t.DurationMin += m.DurationMin
+	t.Load += m.Load
+	t.Capacity += m.Capacity
+	t.Stops += m.Stops
+	t.OnTimeStops += m.OnTimeStops
+	t.LateStops += m.LateStops
+	t.LatenessMin += m.LatenessMin
+}
+
+func utilization(load, capacity int) float64 {
+	if capacity == 0 || capacity >= solver.Unlimited {
+		return 0
+	}
+	return float64(load) / float64(capacity)
+}
+
+func maxUtilization(loads, capacities []int) float64 {
+	highest := 0.0
+	for d := range loads {
+		highest = max(highest, utilization(loads[d], capacities[d]))
+	}
+	return highest
+}
+
+// Compare returns candidate minus base, in total and for every vehicle that
+// appears in either report.
+func Compare(base, candidate Report) Report {
+	delta := Report{Vehicles: []VehicleKPI{}, Total: candidate.Total.sub(base.Total)}
+
+	before := make(map[int]Metrics, len(base.Vehicles))
+	for _, v := range base.Vehicles {
+		before[v.VehicleDBID] = v.Metrics
+	}
+	seen := make(map[int]bool, len(candidate.Vehicles))
+	for _, v := range candidate.Vehicles {
+		seen[v.VehicleDBID] = true
+		delta.Vehicles = append(delta.Vehicles, VehicleKPI{VehicleDBID: v.VehicleDBID, Metrics: v.Metrics.sub(before[v.VehicleDBID])})
+	}
+	for _, v := range base.Vehicles {
+		if !seen[v.VehicleDBID] {
+			delta.Vehicles = append(delta.Vehicles, VehicleKPI{VehicleDBID: v.VehicleDBID, Metrics: Metrics{}.sub(v.Metrics)})
+		}
+	}
+	return delta
+}
+
+func (m Metrics) sub(o Metrics) Metrics {
+	return Metrics{
+		DistanceM:      m.DistanceM - o.DistanceM,
+		DrivingTimeMin: m.DrivingTimeMin - o.DrivingTimeMin,
+		ServiceTimeMin: m.ServiceTimeMin - o.ServiceTimeMin,
+		WaitingTimeMin: m.WaitingTimeMin - o.WaitingTimeMin,
+		DurationMin:    m.DurationMin - o.DurationMin,
+		Load:           m.Load - o.Load,
+		Capacity:       m.Capacity - o.Capacity,
+		Utilization:    m.Utilization - o.Utilization,
+		MaxUtilization: m.MaxUtilization - o.MaxUtilization,
+		Stops:          m.Stops - o.Stops,
+		OnTimeStops:    m.OnTimeStops - o.OnTimeStops,
+		LateStops:      m.LateStops - o.LateStops,
+		LatenessMin:    m.LatenessMin - o.LatenessMin,
+	}
+}
@@ -0,0 +1,113 @@
+package kpi
+
+import (
+	"testing"
+
+	"route-go/internal/db"
+	"route-go/internal/solver"
+)
+
+// onLine lays the orders of p out on a line at the given positions, one per
+// order, with every vehicle starting and ending at 0. Each unit is 1 km and
+// 1 minute of driving.
+func onLine(p *solver.Problem, orderPos ...int) {
+	pos := make([]int, len(p.Nodes))
+	copy(pos, orderPos)
+	p.Dist = make([][]int, len(pos))
+	p.Time = make([][]int, len(pos))
+	for i := range pos {
+		p.Dist[i] = make([]int, len(pos))
+		p.Time[i] = make([]int, len(pos))
+		for j := range pos {
+			d := max(pos[i]-pos[j], pos[j]-pos[i])
+			p.Dist[i][j] = 1000 * d
+			p.Time[i][j] = d
+		}
+	}
+}
+
+func route(vehicleID int, orderIDs ...int) db.VehicleRoute {
+	vr := db.VehicleRoute{VehicleDBID: vehicleID}
+	for _, id := range orderIDs {
+		vr.Route = append(vr.Route, db.Stop{OrderID: id})
+	}
+	vr.Route = append(vr.Route, db.Stop{Type: db.StopTypeEnd})
+	return vr
+}
+
+// testPlan has vehicle 1 serve orders 1 and 2 on time, and vehicle 2
+// reach order 3 after its window closed.
+func testPlan() *solver.Plan {
+	orders := []db.Order{
+		{ID: 1, Demand: 4, ServiceDuration: 5},
+		{ID: 2, Demand: 4, TimeWindows: []any{60.0, 80.0}},
+		{ID: 3, Demand: 5, TimeWindows: []any{0.0, 2.0}},
+	}
+	vehicles := []db.Vehicle{{ID: 1, Capacity: 10}, {ID: 2, Capacity: 5}}
+	sol := db.Solution{Vehicles: []db.VehicleRoute{route(1, 1, 2), route(2, 3)}}
+	plan := solver.NewPlan(sol, orders, vehicles)
+	onLine(plan.Problem, 10, 20, 5)
+	return plan
+}
+
+func TestCompute(t *testing.T) {
+	report := Compute(testPlan())
+
+	// Vehicle 1 leaves as late as order 2's window allows, at 55, and is
+	// back at 100
+	onTime := Metrics{
+		DistanceM: 40000, DrivingTimeMin: 40, ServiceTimeMin: 5, DurationMin: 45,
+		Load: 8, Capacity: 10, Utilization: 0.8, MaxUtilization: 0.8,
+		Stops: 2, OnTimeStops: 2,
+	}
+	// Vehicle 2 cannot make the window, leaves at the start of the day and
+	// arrives 3 min late
+	late := Metrics{
+		DistanceM: 10000, DrivingTimeMin: 10, DurationMin: 10,
+		Load: 5, Capacity: 5, Utilization: 1, MaxUtilization: 1,
+		Stops: 1, LateStops: 1, LatenessMin: 3,
+	}
+	total := Metrics{
+		DistanceM: 50000, DrivingTimeMin: 50, ServiceTimeMin: 5, DurationMin: 55,
+		Load: 13, Capacity: 15, Utilization: 13.0 / 15, MaxUtilization: 13.0 / 15,
+		Stops: 3, OnTimeStops: 2, LateStops: 1, LatenessMin: 3,
+	}
+
+	if len(report.Vehicles) != 2 {
+		t.Fatalf("got %d vehicles, want 2", len(report.Vehicles))
+	}
+	for i, want := range []Metrics{onTime, late} {
+		if got := report.Vehicles[i].Metrics; got != want {
+			t.Errorf("vehicle %d = %+v, want %+v", report.Vehicles[i].VehicleDBID, got, want)
+		}
+	}
+	if report.Total != total {
+		t.Errorf("total = %+v, want %+v", report.Total, total)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := Compute(testPlan())
+	candidate := Report{
+		Vehicles: []VehicleKPI{{VehicleDBID: 1, Metrics: Metrics{DistanceM: 30000, Stops: 3}}},
+		Total:    Metrics{DistanceM: 30000, Stops: 3},
+	}
+	delta := Compare(base, candidate)
+
+	if delta.Total.DistanceM != -20000 || delta.Total.Stops != 0 {
+		t.Errorf("total delta = %+v, want 20 km less over the same stops", delta.Total)
+	}
+	got := make(map[int]Metrics)
+	for _, v := range delta.Vehicles {
+		got[v.VehicleDBID] = v.Metrics
+	}
+	if len(got) != 2 {
+		t.Fatalf("delta has vehicles %v, want 1 and 2", got)
+	}
+	if got[1].DistanceM != -10000 || got[1].Stops != 1 {
+		t.Errorf("vehicle 1 delta = %+v, want 10 km less and one more stop", got[1])
+	}
+	if got[2].DistanceM != -10000 || got[2].Stops != -1 {
+		t.Errorf("vehicle 2 delta = %+v, want its whole route removed", got[2])
+	}
+}
@@ -0,0 +1,115 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"route-go/internal/db"
+)
+
+// Store persists coordinate-pair entries. *db.Repository implements it on
+// top of the matrix_cache table.
+type Store interface {
+	LookupMatrixEntries(ctx context.Context, provider, date string, keys []string) ([]db.MatrixEntry, error)
+	SaveMatrixEntries(ctx context.Context, provider, date string, entries []db.MatrixEntry) error
+}
+
+// Cached serves matrices from the store and only calls the underlying
+// provider when a pair is missing. Entries are kept per planning date, so a
+// date's matrix is computed once however often that date is planned.
+type Cached struct {
+	Provider Provider
+	Store    Store
+	// Date is the planning date, today when zero (see OnDate)
+	Date time.Time
+}
+
+func NewCached(p Provider, s Store) *Cached {
+	return &Cached{Provider: p, Store: s}
+}
+
+func (c *Cached) Name() string {
+	return c.Provider.Name()
+}
+
+// OnDate returns the cache of the given planning date.
+func (c *Cached) OnDate(date time.Time) Provider {
+	dated := *c
+	dated.Date = date
+	return &dated
+}
+
+func (c *Cached) Compute(ctx context.Context, points []Point) (*Matrix, error) {
+	date := c.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	day := date.Format(db.DateLayout)
+
+	keys := make([]string, len(points))
+	for i, p := range points {
+		keys[i] = p.Key()
+	}
+
+	entries, err := c.Store.LookupMatrixEntries(ctx, c.Name(), day, keys)
+	if err != nil {
+		return nil, fmt.Errorf("matrix cache lookup failed: %w", err)
+	}
+	type pair struct{ from, to string }
+	cached := make(map[pair]db.MatrixEntry, len(entries))
+	for _, e := range entries {
+		cached[pair{e.FromKey, e.ToKey}] = e
+	}
+
+	m := newMatrix(len(points))
+	complete := true
+	for i := range points {
+		for j := range points {
+			if keys[i] == keys[j] {
+				continue
+			}
+			e, ok := cached[pair{keys[i], keys[j]}]
+			if !ok {
+				complete = false
+				break
+			}
+			m.Distances[i][j] = e.DistanceM
+			m.Durations[i][j] = e.DurationMin
+		}
+		if !complete {
+			break
+		}
+	}
+	if complete {
+		return m, nil
+	}
+
+	// Compute the whole table in one call; table APIs are priced per request
+	// rather than per pair, so partial refreshes buy nothing.
+	m, err = c.Provider.Compute(ctx, points)
+	if err != nil {
+		return nil, err
+	}
+	// Depots usually appear twice (start and end), only store each pair once
+	var fresh []db.MatrixEntry
+	seen := make(map[pair]bool)
+	for i := range points {
+		for j := range points {
+			if keys[i] == keys[j] || seen[pair{keys[i], keys[j]}] {
+				continue
+			}
+			seen[pair{keys[i], keys[j]}] = true
+			fresh = append(fresh, db.MatrixEntry{
+				FromKey:     keys[i],
+				ToKey:       keys[j],
+				DistanceM:   m.Distances[i][j],
+				DurationMin: m.Durations[i][j],
+			})
+		}
+	}
+	if err := c.Store.SaveMatrixEntries(ctx, c.Name(), day, fresh); err != nil {
+		return nil, fmt.Errorf("matrix cache save failed: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"route-go/internal/db"
+)
+
+// memStore is a Store keeping entries in memory, by date.
+type memStore map[string][]db.MatrixEntry
+
+func (s memStore) LookupMatrixEntries(ctx context.Context, provider, date string, keys []string) ([]db.MatrixEntry, error) {
+	return s[date], nil
+}
+
+func (s memStore) SaveMatrixEntries(ctx context.Context, provider, date string, entries []db.MatrixEntry) error {
+	s[date] = append(s[date], entries...)
+	return nil
+}
+
+// countingProvider counts the matrices it computes.
+type countingProvider struct {
+	Haversine
+	calls int
+}
+
+func (p *countingProvider) Compute(ctx context.Context, points []Point) (*Matrix, error) {
+	p.calls++
+	return p.Haversine.Compute(ctx, points)
+}
+
+func TestCachedKeepsEntriesPerPlanningDate(t *testing.T) {
+	store := memStore{}
+	provider := &countingProvider{}
+	cache := NewCached(provider, store)
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	tuesday := monday.AddDate(0, 0, 1)
+	ctx := context.Background()
+
+	for _, date := range []time.Time{monday, monday, tuesday} {
+		if _, err := OnDate(cache, date).Compute(ctx, twoPoints); err != nil {
+			t.Fatalf("Compute on %s: %v", date.Format(db.DateLayout), err)
+		}
+	}
+
+	if provider.calls != 2 {
+		t.Errorf("provider computed %d matrices, want one per planning date", provider.calls)
+	}
+	for _, day := range []string{"2026-03-02", "2026-03-03"} {
+		if len(store[day]) != 2 {
+			t.Errorf("%s has %d entries, want 2", day, len(store[day]))
+		}
+	}
+}
+
+// failingStore finds nothing and cannot save.
+type failingStore struct{}
+
+func (failingStore) LookupMatrixEntries(ctx context.Context, provider, date string, keys []string) ([]db.MatrixEntry, error) {
+	return nil, nil
+}
+
+func (failingStore) SaveMatrixEntries(ctx context.Context, provider, date string, entries []db.MatrixEntry) error {
+	return errors.New("connection refused")
+}
+
+func TestCachedReportsSaveErrors(t *testing.T) {
+	cache := NewCached(Haversine{}, failingStore{})
+	if _, err := cache.Compute(context.Background(), twoPoints); err == nil {
+		t.Error("Compute succeeded, want the save error")
+	}
+}
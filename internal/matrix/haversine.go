@@ -0,0 +1,51 @@
+package matrix
+
+import (
+	"context"
+	"math"
+)
+
+// AverageSpeedMPerMin is the urban speed used by solver.py (30 km/h)
+const AverageSpeedMPerMin = 500
+
+// Haversine estimates distances as the crow flies and travel time at a
+// constant speed. It needs no network access and never fails.
+type Haversine struct {
+	SpeedMPerMin int
+}
+
+func (h Haversine) Name() string {
+	return "haversine"
+}
+
+func (h Haversine) Compute(ctx context.Context, points []Point) (*Matrix, error) {
+	speed := h.SpeedMPerMin
+	if speed <= 0 {
+		speed = AverageSpeedMPerMin
+	}
+	m := newMatrix(len(points))
+	for i, a := range points {
+		for j, b := range points {
+			if i == j {
+				continue
+			}
+			d := int(Distance(a, b))
+			m.Distances[i][j] = d
+			m.Durations[i][j] = d / speed
+		}
+	}
+	return m, nil
+}
+
+// Distance returns the great-circle distance in meters between two points.
+func Distance(a, b Point) float64 {
+	const earthRadius = 6371000
+	phi1 := a.Lat * math.Pi / 180
+	phi2 := b.Lat * math.Pi / 180
+	dPhi := (b.Lat - a.Lat) * math.Pi / 180
+	dLambda := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return earthRadius * 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+}
@@ -0,0 +1,54 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Key identifies a point in the cache. Coordinates are rounded to ~10cm so
+// the same stop always maps to the same entry.
+func (p Point) Key() string {
+	return fmt.Sprintf("%.6f,%.6f", p.Lat, p.Lon)
+}
+
+// Matrix holds pairwise distances in meters and travel times in minutes,
+// indexed in the same order as the points it was computed for.
+type Matrix struct {
+	Distances [][]int
+	Durations [][]int
+}
+
+func newMatrix(n int) *Matrix {
+	m := &Matrix{Distances: make([][]int, n), Durations: make([][]int, n)}
+	for i := 0; i < n; i++ {
+		m.Distances[i] = make([]int, n)
+		m.Durations[i] = make([]int, n)
+	}
+	return m
+}
+
+// Provider computes a full distance/time matrix for a set of points.
+type Provider interface {
+	// Name is used to keep cache entries of different providers apart
+	Name() string
+	Compute(ctx context.Context, points []Point) (*Matrix, error)
+}
+
+// Dated is implemented by providers that keep matrices per planning date.
+type Dated interface {
+	OnDate(date time.Time) Provider
+}
+
+// OnDate scopes p to the planning date when it keeps matrices per date.
+func OnDate(p Provider, date time.Time) Provider {
+	if d, ok := p.(Dated); ok {
+		return d.OnDate(date)
+	}
+	return p
+}
@@ -0,0 +1,102 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Unreachable is used for pairs the routing engine has no path for, so the
+// solver treats them as prohibitively expensive instead of free.
+const Unreachable = math.MaxInt32
+
+// OSRM queries the table service of an OSRM-compatible routing engine.
+// See http://project-osrm.org/docs/v5.24.0/api/#table-service
+type OSRM struct {
+	BaseURL string // e.g. http://localhost:5000
+	Profile string // defaults to "driving"
+	Client  *http.Client
+}
+
+func NewOSRM(baseURL string) *OSRM {
+	return &OSRM{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Profile: "driving",
+		Client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (o *OSRM) Name() string {
+	return "osrm:" + o.BaseURL
+}
+
+type osrmTableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Distances [][]*float64 `json:"distances"` // meters
+	Durations [][]*float64 `json:"durations"` // seconds
+}
+
+func (o *OSRM) Compute(ctx context.Context, points []Point) (*Matrix, error) {
+	if len(points) == 0 {
+		return newMatrix(0), nil
+	}
+
+	coords := make([]string, len(points))
+	for i, p := range points {
+		// OSRM expects lon,lat
+		coords[i] = fmt.Sprintf("%.6f,%.6f", p.Lon, p.Lat)
+	}
+	profile := o.Profile
+	if profile == "" {
+		profile = "driving"
+	}
+	url := fmt.Sprintf("%s/table/v1/%s/%s?annotations=distance,duration", o.BaseURL, profile, strings.Join(coords, ";"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("osrm table request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body osrmTableResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode osrm response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Code != "Ok" {
+		return nil, fmt.Errorf("osrm table failed: %s %s", body.Code, body.Message)
+	}
+	if len(body.Distances) != len(points) || len(body.Durations) != len(points) {
+		return nil, fmt.Errorf("osrm returned a %dx%d table for %d points", len(body.Distances), len(body.Durations), len(points))
+	}
+
+	m := newMatrix(len(points))
+	for i := range points {
+		if len(body.Distances[i]) != len(points) || len(body.Durations[i]) != len(points) {
+			return nil, fmt.Errorf("osrm returned a short row %d", i)
+		}
+		for j := range points {
+			m.Distances[i][j] = Unreachable
+			m.Durations[i][j] = Unreachable
+			if d := body.Distances[i][j]; d != nil {
+				m.Distances[i][j] = int(math.Round(*d))
+			}
+			if t := body.Durations[i][j]; t != nil {
+				m.Durations[i][j] = int(math.Round(*t / 60))
+			}
+		}
+	}
+	return m, nil
+}
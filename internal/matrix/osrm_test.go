@@ -0,0 +1,86 @@
+package matrix
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeOSRM serves body with status for every table request and records the
+// last request path and query.
+func fakeOSRM(t *testing.T, status int, body string) (*OSRM, *string) {
+	t.Helper()
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path + "?" + r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return NewOSRM(srv.URL + "/"), &got
+}
+
+var twoPoints = []Point{{Lat: -23.5, Lon: -46.6}, {Lat: -23.6, Lon: -46.7}}
+
+func TestOSRMCompute(t *testing.T) {
+	o, got := fakeOSRM(t, http.StatusOK, `{
+		"code": "Ok",
+		"distances": [[0, 1234.4], [1300.6, 0]],
+		"durations": [[0, 150], [null, 0]]
+	}`)
+
+	m, err := o.Compute(context.Background(), twoPoints)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+
+	wantPath := "/table/v1/driving/-46.600000,-23.500000;-46.700000,-23.600000?annotations=distance,duration"
+	if *got != wantPath {
+		t.Errorf("request = %q, want %q", *got, wantPath)
+	}
+	if m.Distances[0][1] != 1234 || m.Distances[1][0] != 1301 {
+		t.Errorf("distances = %v, want meters rounded", m.Distances)
+	}
+	if m.Durations[0][1] != 3 {
+		t.Errorf("duration 0->1 = %d, want 150s as 3 min", m.Durations[0][1])
+	}
+	if m.Durations[1][0] != Unreachable {
+		t.Errorf("duration 1->0 = %d, want Unreachable for null", m.Durations[1][0])
+	}
+}
+
+func TestOSRMComputeNoPoints(t *testing.T) {
+	o := NewOSRM("http://127.0.0.1:0") // never called
+	m, err := o.Compute(context.Background(), nil)
+	if err != nil || len(m.Distances) != 0 {
+		t.Fatalf("Compute(nil) = %v, %v, want an empty matrix", m, err)
+	}
+}
+
+func TestOSRMComputeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"not ok", http.StatusBadRequest, `{"code": "InvalidQuery", "message": "Query string malformed"}`, "InvalidQuery Query string malformed"},
+		{"not ok with 200", http.StatusOK, `{"code": "NoTable", "message": "no route"}`, "NoTable"},
+		{"not json", http.StatusBadGateway, `<html>bad gateway</html>`, "failed to decode osrm response (status 502)"},
+		{"too few rows", http.StatusOK, `{"code": "Ok", "distances": [[0, 1]], "durations": [[0, 1]]}`, "1x1 table for 2 points"},
+		{"short row", http.StatusOK, `{"code": "Ok", "distances": [[0, 1], [1]], "durations": [[0, 1], [1, 0]]}`, "short row 1"},
+		{"missing durations", http.StatusOK, `{"code": "Ok", "distances": [[0, 1], [1, 0]]}`, "2x0 table"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, _ := fakeOSRM(t, tt.status, tt.body)
+			_, err := o.Compute(context.Background(), twoPoints)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
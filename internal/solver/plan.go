@@ -0,0 +1,147 @@
+package solver
+
+import (
+	"route-go/internal/db"
+)
+
+// Plan maps an existing solution onto a Problem so it can be scheduled,
+// checked and measured with the same model the solver uses.
+type Plan struct {
+	Problem *Problem
+	// Routes follows the order of Solution.Vehicles
+	Routes []PlanRoute
+	// OrderNode maps order IDs to node indices
+	OrderNode map[int]int
+}
+
+type PlanRoute struct {
+	VehicleDBID int
+	Vehicle     int // index in Problem.Vehicles, -1 when the vehicle is unknown
+	Seq         []int
+	// Missing lists order IDs in the route that were not in the given orders
+	Missing []int
+}
+
+// NewPlan builds a problem from the given orders and vehicles and lays out
+// sol on it. Orders and vehicles the solution references but that are not
+// provided are reported instead of failing.
+func NewPlan(sol db.Solution, orders []db.Order, vehicles []db.Vehicle) *Plan {
+	plan := &Plan{Problem: NewProblem(orders, vehicles), OrderNode: make(map[int]int, len(orders))}
+	for i, o := range orders {
+		plan.OrderNode[o.ID] = i
+	}
+	vehicleIndex := make(map[int]int, len(vehicles))
+	for i, v := range vehicles {
+		vehicleIndex[v.ID] = i
+	}
+
+	for _, vr := range sol.Vehicles {
+		pr := PlanRoute{VehicleDBID: vr.VehicleDBID, Vehicle: -1}
+		if idx, ok := vehicleIndex[vr.VehicleDBID]; ok {
+			pr.Vehicle = idx
+		}
+		for _, id := range vr.OrderIDs() {
+			if node, ok := plan.OrderNode[id]; ok {
+				pr.Seq = append(pr.Seq, node)
+			} else {
+				pr.Missing = append(pr.Missing, id)
+			}
+		}
+		plan.Routes = append(plan.Routes, pr)
+	}
+	return plan
+}
+
+// Recompute returns the solution described by the plan, with the stops of
+// every known vehicle rebuilt: arrival window, waiting time, lateness and
+// route distance. sol supplies the routes of unknown vehicles, which are
+// passed through untouched.
+func (plan *Plan) Recompute(sol db.Solution) db.Solution {
+	p := plan.Problem
+	out := db.Solution{SchemaVersion: db.SolutionSchemaVersion, Vehicles: make([]db.VehicleRoute, len(plan.Routes))}
+	for i, pr := range plan.Routes {
+		if pr.Vehicle < 0 {
+			out.Vehicles[i] = sol.Vehicles[i]
+			continue
+		}
+
+		visits := p.Timeline(pr.Vehicle, pr.Seq)
+		rebuilt := db.VehicleRoute{VehicleDBID: pr.VehicleDBID, TotalDistanceM: p.Distance(pr.Vehicle, pr.Seq)}
+		for j, visit := range visits {
+			node := p.Nodes[visit.Node]
+			stop := db.Stop{
+				NodeIndex:    visit.Node,
+				MinTime:      visit.MinTime,
+				MaxTime:      visit.MaxTime,
+				OrderID:      node.OrderID,
+				CustomerID:   node.CustomerID,
+				CustomerName: node.CustomerName,
+				WaitTime:     visit.Wait,
+				Lateness:     visit.Late,
+			}
+			if j == len(visits)-1 {
+				stop.Type = db.StopTypeEnd
+			}
+			rebuilt.Route = append(rebuilt.Route, stop)
+		}
+		out.Vehicles[i] = rebuilt
+	}
+	return out
+}
+
+// Insert adds the given orders at their cheapest feasible positions without
+// reordering existing stops. Vehicles of the problem without a route in the
+// plan get one when they receive an order. Either half of a pickup-and-delivery pair brings the other
+// along. It returns the orders that fit nowhere, and why.
+func (plan *Plan) Insert(orderIDs []int) []db.UnassignedOrder {
+	p := plan.Problem
+	s := &search{p: p, routes: make([][]int, len(p.Vehicles))}
+
+	routeOf := make(map[int]int, len(p.Vehicles)) // vehicle index -> plan route
+	for i, pr := range plan.Routes {
+		if _, dup := routeOf[pr.Vehicle]; pr.Vehicle >= 0 && !dup {
+			routeOf[pr.Vehicle] = i
+			s.routes[pr.Vehicle] = append([]int(nil), pr.Seq...)
+		}
+	}
+
+	routed := make(map[int]bool)
+	for _, seq := range s.routes {
+		for _, n := range seq {
+			routed[n] = true
+		}
+	}
+
+	var nodes []int
+	var failed []db.UnassignedOrder
+	seen := make(map[int]bool)
+	for _, id := range orderIDs {
+		node, ok := plan.OrderNode[id]
+		if !ok {
+			continue
+		}
+		unit := p.Unit(node)
+		if routed[unit[0]] || routed[unit[len(unit)-1]] {
+			failed = append(failed, db.UnassignedOrder{OrderID: id, Reason: "its pair is already routed", Priority: p.Nodes[node].Priority})
+			continue
+		}
+		if !seen[unit[0]] {
+			seen[unit[0]] = true
+			nodes = append(nodes, unit[0])
+		}
+	}
+
+	for _, lead := range s.insertByPenalty(nodes) {
+		for _, node := range p.Unit(lead) {
+			failed = append(failed, db.UnassignedOrder{OrderID: p.Nodes[node].OrderID, Reason: p.UnassignedReason(node), Priority: p.Nodes[node].Priority})
+		}
+	}
+	for v, seq := range s.routes {
+		if i, ok := routeOf[v]; ok {
+			plan.Routes[i].Seq = seq
+		} else if len(seq) > 0 {
+			plan.Routes = append(plan.Routes, PlanRoute{VehicleDBID: p.Vehicles[v].ID, Vehicle: v, Seq: seq})
+		}
+	}
+	return failed
+}
@@ -0,0 +1,34 @@
+package solver
+
+import (
+	"reflect"
+	"testing"
+
+	"route-go/internal/db"
+)
+
+func TestPlanInsert(t *testing.T) {
+	orders := []db.Order{{ID: 1, Demand: 6}, {ID: 2, Demand: 6}}
+	vehicles := []db.Vehicle{{ID: 1, Capacity: 10}, {ID: 2, Capacity: 10}, {ID: 3, Capacity: 10}}
+	sol := db.Solution{Vehicles: []db.VehicleRoute{
+		{VehicleDBID: 1, Route: []db.Stop{{OrderID: 1}, {Type: db.StopTypeEnd}}},
+	}}
+	plan := NewPlan(sol, orders, vehicles)
+	onLine(plan.Problem, 10, 20)
+
+	if failed := plan.Insert([]int{2}); len(failed) > 0 {
+		t.Fatalf("Insert failed %v, want order 2 inserted", failed)
+	}
+	// Vehicle 1 is full, so one idle vehicle takes order 2 and the other
+	// gets no route
+	got := plan.Recompute(sol)
+	if len(got.Vehicles) != 2 {
+		t.Fatalf("got %d routes, want 2", len(got.Vehicles))
+	}
+	if ids := got.Vehicles[0].OrderIDs(); !reflect.DeepEqual(ids, []int{1}) {
+		t.Errorf("vehicle 1 route %v, want [1]", ids)
+	}
+	if ids := got.Vehicles[1].OrderIDs(); !reflect.DeepEqual(ids, []int{2}) {
+		t.Errorf("vehicle %d route %v, want [2]", got.Vehicles[1].VehicleDBID, ids)
+	}
+}
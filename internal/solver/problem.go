@@ -0,0 +1,397 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"route-go/internal/db"
+	"route-go/internal/matrix"
+)
+
+// Same assumptions as optimization/solver.py. DropPenalty applies to
+// priorities without a configured penalty.
+const (
+	DayStart         = 0
+	DayEnd           = 1440
+	VehicleFixedCost = 100000
+	DropPenalty      = 1000000
+)
+
+// Unlimited is the capacity of a vehicle in a dimension it does not declare.
+const Unlimited = math.MaxInt32
+
+// Window is a time window in minutes from midnight.
+type Window struct {
+	Start int
+	End   int
+}
+
+// Node is a location in the problem. Orders come first, followed by a
+// start and an end node for each vehicle, mirroring solver.py so that
+// node_index values stay comparable between both engines.
+type Node struct {
+	OrderID      int
+	CustomerID   int
+	CustomerName string
+	Lat          float64
+	Lon          float64
+	Demand       []int // per dimension, nil for depot nodes
+	Service      int
+	Windows      []Window
+	Skills       []string // required of the serving vehicle
+	// Pickup orders are loaded on site rather than at the depot
+	Pickup bool
+	// Pair is the node index of the other half of a pickup-and-delivery
+	// pair, -1 when unpaired or the other half is not in the problem
+	Pair        int
+	PairOrderID int // 0 when unpaired
+	Priority    string
+	// DepotID is the depot whose vehicles must serve the order, 0 for any
+	DepotID int
+}
+
+type Vehicle struct {
+	ID       int
+	Capacity []int // per dimension
+	Start    int   // node index
+	End      int   // node index
+	// Open routes finish at the last stop: reaching End is free
+	Open   bool
+	Skills map[string]bool
+	// DepotID is the vehicle's home depot, 0 for none
+	DepotID int
+	// DayOff vehicles do not work on the planned day and serve nothing
+	DayOff bool
+}
+
+type Problem struct {
+	// Dimensions names the capacity dimensions, weight first
+	Dimensions []string
+	// Penalties is the cost of leaving out an order, by priority
+	Penalties map[string]int
+	Nodes     []Node
+	Vehicles  []Vehicle
+	NumOrders int
+	Dist      [][]int // meters
+	Time      [][]int // minutes
+}
+
+// NewProblem builds the node layout for the given orders and vehicles.
+// LoadMatrix must be called before solving.
+func NewProblem(orders []db.Order, vehicles []db.Vehicle) *Problem {
+	p := &Problem{NumOrders: len(orders), Dimensions: dimensionsOf(orders, vehicles), Penalties: db.DefaultDropPenalties}
+	index := make(map[int]int, len(orders))
+	for i, o := range orders {
+		index[o.ID] = i
+	}
+	for _, o := range orders {
+		demand := make([]int, len(p.Dimensions))
+		for d, name := range p.Dimensions {
+			demand[d] = o.Quantities[name]
+		}
+		if o.Quantities == nil {
+			demand[0] = o.Demand
+		}
+		p.Nodes = append(p.Nodes, Node{
+			OrderID:      o.ID,
+			CustomerID:   o.CustomerID,
+			CustomerName: o.CustomerName,
+			Lat:          o.Lat,
+			Lon:          o.Lon,
+			Demand:       demand,
+			Service:      o.ServiceDuration,
+			Windows:      ParseTimeWindows(o.TimeWindows),
+			Skills:       o.RequiredSkills,
+			Pickup:       o.IsPickup(),
+			Pair:         pairIndex(o, index),
+			PairOrderID:  pairOrderID(o),
+			Priority:     o.Priority,
+			DepotID:      derefID(o.DepotID),
+		})
+	}
+	for _, v := range vehicles {
+		depot := []Window{{DayStart, DayEnd}}
+		start := len(p.Nodes)
+		p.Nodes = append(p.Nodes, Node{Lat: v.StartLat, Lon: v.StartLon, Windows: depot, Pair: -1})
+		end := len(p.Nodes)
+		capacity := make([]int, len(p.Dimensions))
+		for d, name := range p.Dimensions {
+			c, ok := v.Capacities[name]
+			if !ok {
+				c = Unlimited
+			}
+			capacity[d] = c
+		}
+		if v.Capacities == nil {
+			capacity[0] = v.Capacity
+		}
+		skills := make(map[string]bool, len(v.Skills))
+		for _, s := range v.Skills {
+			skills[s] = true
+		}
+		endLat, endLon := v.EndLocation()
+		p.Nodes = append(p.Nodes, Node{Lat: endLat, Lon: endLon, Windows: depot, Pair: -1})
+		p.Vehicles = append(p.Vehicles, Vehicle{ID: v.ID, Capacity: capacity, Start: start, End: end, Open: v.RouteMode == db.RouteModeOpen, Skills: skills, DepotID: derefID(v.DepotID)})
+	}
+	return p
+}
+
+func derefID(id *int) int {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func pairOrderID(o db.Order) int {
+	if o.PairOrderID == nil {
+		return 0
+	}
+	return *o.PairOrderID
+}
+
+func pairIndex(o db.Order, index map[int]int) int {
+	if o.PairOrderID == nil {
+		return -1
+	}
+	if i, ok := index[*o.PairOrderID]; ok {
+		return i
+	}
+	return -1
+}
+
+// Unit is node n together with its pair, if any, pickup first: the orders
+// that have to be routed or dropped together.
+func (p *Problem) Unit(n int) []int {
+	node := p.Nodes[n]
+	switch {
+	case node.Pair < 0:
+		return []int{n}
+	case node.Pickup:
+		return []int{n, node.Pair}
+	default:
+		return []int{node.Pair, n}
+	}
+}
+
+// Penalty is the cost of leaving out order node n.
+func (p *Problem) Penalty(n int) int {
+	if penalty, ok := p.Penalties[p.Nodes[n].Priority]; ok {
+		return penalty
+	}
+	return DropPenalty
+}
+
+// MissingSkills lists the skills node n requires that vehicle v lacks.
+func (p *Problem) MissingSkills(v, n int) []string {
+	var missing []string
+	for _, s := range p.Nodes[n].Skills {
+		if !p.Vehicles[v].Skills[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// InDepot reports whether vehicle v belongs to the depot fulfilling order
+// node n. Orders without a depot can go on any vehicle.
+func (p *Problem) InDepot(v, n int) bool {
+	depot := p.Nodes[n].DepotID
+	return depot == 0 || depot == p.Vehicles[v].DepotID
+}
+
+// CanServe reports whether vehicle v works that day, is from node n's depot
+// and has every skill it requires.
+func (p *Problem) CanServe(v, n int) bool {
+	if p.Vehicles[v].DayOff || !p.InDepot(v, n) {
+		return false
+	}
+	for _, s := range p.Nodes[n].Skills {
+		if !p.Vehicles[v].Skills[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// UnassignedReason explains why order node n could not be routed, going from
+// what no vehicle offers to what is merely used up.
+func (p *Problem) UnassignedReason(n int) string {
+	unit := p.Unit(n)
+	first, last := unit[0], unit[len(unit)-1]
+	working := 0
+	var local, skilled []int
+	for v := range p.Vehicles {
+		if p.Vehicles[v].DayOff {
+			continue
+		}
+		working++
+		if !p.InDepot(v, first) || !p.InDepot(v, last) {
+			continue
+		}
+		local = append(local, v)
+		if p.CanServe(v, first) && p.CanServe(v, last) {
+			skilled = append(skilled, v)
+		}
+	}
+	if working == 0 {
+		return "no vehicles available"
+	}
+	if len(local) == 0 {
+		a, b := p.Nodes[first].DepotID, p.Nodes[last].DepotID
+		if a != 0 && b != 0 && a != b {
+			return fmt.Sprintf("pickup and delivery belong to different depots, %d and %d", a, b)
+		}
+		return fmt.Sprintf("no vehicle from depot %d", max(a, b))
+	}
+	if len(skilled) == 0 {
+		var missing []string
+		for _, s := range p.Nodes[n].Skills {
+			offered := false
+			for _, v := range local {
+				offered = offered || p.Vehicles[v].Skills[s]
+			}
+			if !offered {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) > 0 {
+			return "no vehicle has skill " + strings.Join(missing, ", ")
+		}
+		return "no single vehicle has all of " + strings.Join(p.Nodes[n].Skills, ", ")
+	}
+
+	fits := false
+	for _, v := range skilled {
+		fits = fits || p.Fits(v, p.PeakLoad(unit))
+	}
+	if !fits {
+		return "demand exceeds the capacity of every suitable vehicle"
+	}
+	for _, v := range skilled {
+		if _, ok := p.Schedule(v, unit); ok {
+			return "no room left on a suitable vehicle within capacity and time windows"
+		}
+	}
+	return "time window cannot be met by any suitable vehicle"
+}
+
+// dimensionsOf lists every dimension used by the orders or vehicles: weight
+// first, the rest sorted by name.
+func dimensionsOf(orders []db.Order, vehicles []db.Vehicle) []string {
+	seen := map[string]bool{db.DimensionWeight: true}
+	var others []string
+	add := func(q db.Quantities) {
+		for name := range q {
+			if !seen[name] {
+				seen[name] = true
+				others = append(others, name)
+			}
+		}
+	}
+	for _, o := range orders {
+		add(o.Quantities)
+	}
+	for _, v := range vehicles {
+		add(v.Capacities)
+	}
+	sort.Strings(others)
+	return append([]string{db.DimensionWeight}, others...)
+}
+
+// RestrictVehicle narrows when vehicle v may leave and must be back, e.g. to
+// its depot's opening hours or the driver's shift. Restrictions add up.
+func (p *Problem) RestrictVehicle(v int, w Window) {
+	veh := p.Vehicles[v]
+	cur := p.Nodes[veh.Start].Windows[0]
+	w.Start = max(w.Start, cur.Start)
+	w.End = max(min(w.End, cur.End), w.Start)
+	p.Nodes[veh.Start].Windows = []Window{w}
+	p.Nodes[veh.End].Windows = []Window{w}
+}
+
+// Points lists the node locations in node index order.
+func (p *Problem) Points() []matrix.Point {
+	points := make([]matrix.Point, len(p.Nodes))
+	for i, n := range p.Nodes {
+		points[i] = matrix.Point{Lat: n.Lat, Lon: n.Lon}
+	}
+	return points
+}
+
+// LoadMatrix fills the distance/time matrix from the given provider.
+// Arcs into the end node of an open route cost nothing.
+func (p *Problem) LoadMatrix(ctx context.Context, provider matrix.Provider) error {
+	m, err := provider.Compute(ctx, p.Points())
+	if err != nil {
+		return fmt.Errorf("failed to compute matrix: %w", err)
+	}
+	p.Dist, p.Time = m.Distances, m.Durations
+	for _, v := range p.Vehicles {
+		if !v.Open {
+			continue
+		}
+		for i := range p.Nodes {
+			p.Dist[i][v.End] = 0
+			p.Time[i][v.End] = 0
+		}
+	}
+	return nil
+}
+
+// ParseTimeWindows accepts the same shapes solver.py does:
+// [start, end], [[start, end], ...] and [{"start": s, "end": e}, ...].
+// Anything else (or an empty list) means the whole day.
+func ParseTimeWindows(raw any) []Window {
+	var windows []Window
+	list, _ := raw.([]any)
+
+	if len(list) == 2 {
+		start, ok1 := toInt(list[0])
+		end, ok2 := toInt(list[1])
+		if ok1 && ok2 {
+			list = nil
+			windows = append(windows, Window{start, end})
+		}
+	}
+
+	for _, item := range list {
+		w := Window{DayStart, DayEnd}
+		switch tw := item.(type) {
+		case map[string]any:
+			if v, ok := toInt(tw["start"]); ok {
+				w.Start = v
+			}
+			if v, ok := toInt(tw["end"]); ok {
+				w.End = v
+			}
+		case []any:
+			if len(tw) >= 2 {
+				w.Start, _ = toInt(tw[0])
+				w.End, _ = toInt(tw[1])
+			}
+		}
+		windows = append(windows, w)
+	}
+
+	if len(windows) == 0 {
+		windows = append(windows, Window{DayStart, DayEnd})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start < windows[j].Start })
+	return windows
+}
+
+func toInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	}
+	return 0, false
+}
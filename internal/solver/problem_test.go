@@ -0,0 +1,151 @@
+package solver
+
+import (
+	"reflect"
+	"testing"
+
+	"route-go/internal/db"
+)
+
+// onLine lays the orders of p out on a line at the given positions, one per
+// order, with every vehicle starting and ending at 0. Each unit is 1 km and
+// 1 minute of driving.
+func onLine(p *Problem, orderPos ...int) {
+	pos := make([]int, len(p.Nodes))
+	copy(pos, orderPos)
+	p.Dist = make([][]int, len(pos))
+	p.Time = make([][]int, len(pos))
+	for i := range pos {
+		p.Dist[i] = make([]int, len(pos))
+		p.Time[i] = make([]int, len(pos))
+		for j := range pos {
+			d := max(pos[i]-pos[j], pos[j]-pos[i])
+			p.Dist[i][j] = 1000 * d
+			p.Time[i][j] = d
+		}
+	}
+}
+
+// window is a time_windows value as decoded from JSON.
+func window(start, end int) any {
+	return []any{float64(start), float64(end)}
+}
+
+func depot(id int) *int {
+	return &id
+}
+
+func TestParseTimeWindows(t *testing.T) {
+	day := []Window{{DayStart, DayEnd}}
+	tests := []struct {
+		name string
+		raw  any
+		want []Window
+	}{
+		{"nil", nil, day},
+		{"empty", []any{}, day},
+		{"not a list", "08:00-10:00", day},
+		{"flat pair", []any{480.0, 600.0}, []Window{{480, 600}}},
+		{"list of pairs, sorted", []any{[]any{600.0, 700.0}, []any{480.0, 540.0}}, []Window{{480, 540}, {600, 700}}},
+		{"objects", []any{map[string]any{"start": 480.0, "end": 600.0}}, []Window{{480, 600}}},
+		{"object without end", []any{map[string]any{"start": 480.0}}, []Window{{480, DayEnd}}},
+		{"malformed item", []any{"morning"}, day},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTimeWindows(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTimeWindows(%v) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnassignedReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    db.Order
+		vehicles []db.Vehicle
+		want     string
+	}{
+		{
+			name:  "no vehicles",
+			order: db.Order{ID: 1, Demand: 1},
+			want:  "no vehicles available",
+		},
+		{
+			name:     "other depot",
+			order:    db.Order{ID: 1, Demand: 1, DepotID: depot(2)},
+			vehicles: []db.Vehicle{{ID: 1, Capacity: 10, DepotID: depot(1)}},
+			want:     "no vehicle from depot 2",
+		},
+		{
+			name:     "skill nobody has",
+			order:    db.Order{ID: 1, Demand: 1, RequiredSkills: []string{"refrigerated"}},
+			vehicles: []db.Vehicle{{ID: 1, Capacity: 10, Skills: []string{"tail_lift"}}},
+			want:     "no vehicle has skill refrigerated",
+		},
+		{
+			name:  "skills split over vehicles",
+			order: db.Order{ID: 1, Demand: 1, RequiredSkills: []string{"refrigerated", "tail_lift"}},
+			vehicles: []db.Vehicle{
+				{ID: 1, Capacity: 10, Skills: []string{"refrigerated"}},
+				{ID: 2, Capacity: 10, Skills: []string{"tail_lift"}},
+			},
+			want: "no single vehicle has all of refrigerated, tail_lift",
+		},
+		{
+			name:     "too heavy",
+			order:    db.Order{ID: 1, Demand: 11},
+			vehicles: []db.Vehicle{{ID: 1, Capacity: 10}},
+			want:     "demand exceeds the capacity of every suitable vehicle",
+		},
+		{
+			name:     "window out of reach",
+			order:    db.Order{ID: 1, Demand: 1, TimeWindows: window(0, 5)},
+			vehicles: []db.Vehicle{{ID: 1, Capacity: 10}},
+			want:     "time window cannot be met by any suitable vehicle",
+		},
+		{
+			name:     "feasible alone",
+			order:    db.Order{ID: 1, Demand: 1},
+			vehicles: []db.Vehicle{{ID: 1, Capacity: 10}},
+			want:     "no room left on a suitable vehicle within capacity and time windows",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProblem([]db.Order{tt.order}, tt.vehicles)
+			onLine(p, 10)
+			if got := p.UnassignedReason(0); got != tt.want {
+				t.Errorf("UnassignedReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanServe(t *testing.T) {
+	orders := []db.Order{
+		{ID: 1, Demand: 1},
+		{ID: 2, Demand: 1, DepotID: depot(1)},
+		{ID: 3, Demand: 1, DepotID: depot(1), RequiredSkills: []string{"refrigerated"}},
+	}
+	vehicles := []db.Vehicle{
+		{ID: 1, Capacity: 10, DepotID: depot(1)},
+		{ID: 2, Capacity: 10, DepotID: depot(2), Skills: []string{"refrigerated"}},
+		{ID: 3, Capacity: 10},
+	}
+	p := NewProblem(orders, vehicles)
+	want := [][]bool{
+		// order 1, 2, 3
+		{true, true, false},  // depot 1
+		{true, false, false}, // depot 2
+		{true, false, false}, // no depot
+	}
+	for v := range vehicles {
+		for n := range orders {
+			if got := p.CanServe(v, n); got != want[v][n] {
+				t.Errorf("CanServe(vehicle %d, order %d) = %v, want %v", vehicles[v].ID, orders[n].ID, got, want[v][n])
+			}
+		}
+	}
+}
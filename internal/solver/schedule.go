@@ -0,0 +1,203 @@
+package solver
+
+// Visit is the timing of a single node in a vehicle's schedule.
+// MinTime/MaxTime match the cumul bounds OR-Tools reports: the earliest
+// and latest moment service can begin without breaking the rest of the route.
+type Visit struct {
+	Node    int
+	Arrival int
+	MinTime int
+	MaxTime int
+	Wait    int
+	Late    int // minutes past the closing of the last window, Timeline only
+}
+
+// Schedule computes the timeline of vehicle v serving seq (order node
+// indices, depot nodes excluded). The returned visits include the start and
+// end depot. ok is false when a time window, the capacity, a depot or skill
+// requirement or a pickup-and-delivery pair is violated.
+func (p *Problem) Schedule(v int, seq []int) ([]Visit, bool) {
+	veh := p.Vehicles[v]
+	if !p.Fits(v, p.PeakLoad(seq)) || len(p.BrokenPairs(seq)) > 0 {
+		return nil, false
+	}
+	for _, n := range seq {
+		if !p.CanServe(v, n) {
+			return nil, false
+		}
+	}
+
+	nodes := make([]int, 0, len(seq)+2)
+	nodes = append(nodes, veh.Start)
+	nodes = append(nodes, seq...)
+	nodes = append(nodes, veh.End)
+
+	visits := make([]Visit, len(nodes))
+	startWindows := p.Nodes[veh.Start].Windows
+	visits[0] = Visit{Node: veh.Start, Arrival: startWindows[0].Start, MinTime: startWindows[0].Start}
+
+	// Forward pass: earliest service start
+	for i := 1; i < len(nodes); i++ {
+		prev, cur := nodes[i-1], nodes[i]
+		arrival := visits[i-1].MinTime + p.Nodes[prev].Service + p.Time[prev][cur]
+		start, ok := earliestStart(p.Nodes[cur].Windows, arrival)
+		if !ok {
+			return nil, false
+		}
+		visits[i] = Visit{Node: cur, Arrival: arrival, MinTime: start, Wait: start - arrival}
+	}
+
+	// Backward pass: latest service start that keeps successors feasible
+	last := len(nodes) - 1
+	endWindows := p.Nodes[veh.End].Windows
+	visits[last].MaxTime = endWindows[len(endWindows)-1].End
+	for i := last - 1; i >= 0; i-- {
+		cur, next := nodes[i], nodes[i+1]
+		bound := visits[i+1].MaxTime - p.Time[cur][next] - p.Nodes[cur].Service
+		latest, ok := latestStart(p.Nodes[cur].Windows, bound)
+		if !ok || latest < visits[i].MinTime {
+			return nil, false
+		}
+		visits[i].MaxTime = latest
+	}
+
+	return visits, true
+}
+
+// Timeline is like Schedule but never gives up: a stop reached after its
+// windows close is served on arrival and its lateness recorded, and capacity
+// is not checked. It is meant for reporting on hand-edited routes.
+func (p *Problem) Timeline(v int, seq []int) []Visit {
+	if visits, ok := p.Schedule(v, seq); ok {
+		return visits
+	}
+	visits := p.Simulate(v, seq, p.Nodes[p.Vehicles[v].Start].Windows[0].Start)
+	for i := range visits {
+		visits[i].MaxTime = visits[i].MinTime
+	}
+	return visits
+}
+
+// Simulate drives seq leaving the depot at depart, waiting for windows to
+// open and recording lateness when they are already closed. MinTime is the
+// service start; MaxTime is left unset.
+func (p *Problem) Simulate(v int, seq []int, depart int) []Visit {
+	veh := p.Vehicles[v]
+	nodes := make([]int, 0, len(seq)+2)
+	nodes = append(nodes, veh.Start)
+	nodes = append(nodes, seq...)
+	nodes = append(nodes, veh.End)
+
+	visits := make([]Visit, len(nodes))
+	visits[0] = Visit{Node: veh.Start, Arrival: depart, MinTime: depart}
+	for i := 1; i < len(nodes); i++ {
+		prev, cur := nodes[i-1], nodes[i]
+		arrival := visits[i-1].MinTime + p.Nodes[prev].Service + p.Time[prev][cur]
+		visit := Visit{Node: cur, Arrival: arrival, MinTime: arrival}
+		windows := p.Nodes[cur].Windows
+		if start, ok := earliestStart(windows, arrival); ok {
+			visit.MinTime, visit.Wait = start, start-arrival
+		} else {
+			visit.Late = arrival - windows[len(windows)-1].End
+		}
+		visits[i] = visit
+	}
+	return visits
+}
+
+// Load is the total demand per dimension carried out of the depot for seq:
+// its unpaired deliveries.
+func (p *Problem) Load(seq []int) []int {
+	load := make([]int, len(p.Dimensions))
+	for _, n := range seq {
+		node := p.Nodes[n]
+		if node.Pickup || node.Pair >= 0 {
+			continue
+		}
+		for d, q := range node.Demand {
+			load[d] += q
+		}
+	}
+	return load
+}
+
+// PeakLoad is the most the vehicle carries per dimension at any point of
+// seq: what leaves the depot, plus pickups until they are delivered.
+func (p *Problem) PeakLoad(seq []int) []int {
+	load := p.Load(seq)
+	peak := append([]int(nil), load...)
+	for _, n := range seq {
+		node := p.Nodes[n]
+		sign := -1
+		if node.Pickup {
+			sign = 1
+		}
+		for d, q := range node.Demand {
+			load[d] += sign * q
+			peak[d] = max(peak[d], load[d])
+		}
+	}
+	return peak
+}
+
+// BrokenPairs lists the nodes of seq whose pair is missing from seq or
+// visited in the wrong order (the delivery before its pickup).
+func (p *Problem) BrokenPairs(seq []int) []int {
+	var broken []int
+	pos := make(map[int]int, len(seq))
+	for i, n := range seq {
+		pos[n] = i
+	}
+	for i, n := range seq {
+		node := p.Nodes[n]
+		if node.Pair < 0 {
+			continue
+		}
+		j, ok := pos[node.Pair]
+		if !ok || (node.Pickup && j < i) || (!node.Pickup && j > i) {
+			broken = append(broken, n)
+		}
+	}
+	return broken
+}
+
+// Fits reports whether vehicle v can carry load in every dimension.
+func (p *Problem) Fits(v int, load []int) bool {
+	for d, q := range load {
+		if q > p.Vehicles[v].Capacity[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// Distance is the length in meters of vehicle v serving seq.
+func (p *Problem) Distance(v int, seq []int) int {
+	veh := p.Vehicles[v]
+	if len(seq) == 0 {
+		return 0
+	}
+	d := p.Dist[veh.Start][seq[0]]
+	for i := 1; i < len(seq); i++ {
+		d += p.Dist[seq[i-1]][seq[i]]
+	}
+	return d + p.Dist[seq[len(seq)-1]][veh.End]
+}
+
+func earliestStart(windows []Window, arrival int) (int, bool) {
+	for _, w := range windows {
+		if arrival <= w.End {
+			return max(arrival, w.Start), true
+		}
+	}
+	return 0, false
+}
+
+func latestStart(windows []Window, bound int) (int, bool) {
+	for i := len(windows) - 1; i >= 0; i-- {
+		if windows[i].Start <= bound {
+			return min(bound, windows[i].End), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,99 @@
+package solver
+
+import (
+	"testing"
+
+	"route-go/internal/db"
+)
+
+func TestSchedule(t *testing.T) {
+	// Stops at 10 and 20 min from the depot; a opens at 30 and takes 5 min
+	a := db.Order{ID: 1, Demand: 4, TimeWindows: window(30, 60), ServiceDuration: 5}
+	b := db.Order{ID: 2, Demand: 4}
+	vehicle := db.Vehicle{ID: 1, Capacity: 10}
+
+	t.Run("waits for the window to open", func(t *testing.T) {
+		p := NewProblem([]db.Order{a, b}, []db.Vehicle{vehicle})
+		onLine(p, 10, 20)
+		visits, ok := p.Schedule(0, []int{0, 1})
+		if !ok {
+			t.Fatal("Schedule failed, want a feasible route")
+		}
+		want := []Visit{
+			{Node: 2, Arrival: 0, MinTime: 0, MaxTime: 50},
+			{Node: 0, Arrival: 10, MinTime: 30, MaxTime: 60, Wait: 20},
+			{Node: 1, Arrival: 45, MinTime: 45, MaxTime: DayEnd - 20},
+			{Node: 3, Arrival: 65, MinTime: 65, MaxTime: DayEnd},
+		}
+		if len(visits) != len(want) {
+			t.Fatalf("got %d visits, want %d", len(visits), len(want))
+		}
+		for i := range want {
+			if visits[i] != want[i] {
+				t.Errorf("visit %d = %+v, want %+v", i, visits[i], want[i])
+			}
+		}
+	})
+
+	tests := []struct {
+		name     string
+		b        db.Order
+		seq      []int
+		closesAt int // the depot's, DayEnd when 0
+		want     bool
+	}{
+		{"b closes before it is reached", db.Order{ID: 2, Demand: 4, TimeWindows: window(0, 20)}, []int{0, 1}, 0, false},
+		{"b first fits its window", db.Order{ID: 2, Demand: 4, TimeWindows: window(0, 20)}, []int{1, 0}, 0, true},
+		{"back after the depot closes", b, []int{0, 1}, 60, false},
+		{"back before the depot closes", b, []int{0, 1}, 65, true},
+		{"over capacity", db.Order{ID: 2, Demand: 7}, []int{0, 1}, 0, false},
+		{"at capacity", db.Order{ID: 2, Demand: 6}, []int{0, 1}, 0, true},
+		{"missing skill", db.Order{ID: 2, Demand: 4, RequiredSkills: []string{"refrigerated"}}, []int{0, 1}, 0, false},
+		{"other depot", db.Order{ID: 2, Demand: 4, DepotID: depot(3)}, []int{0, 1}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProblem([]db.Order{a, tt.b}, []db.Vehicle{vehicle})
+			onLine(p, 10, 20)
+			if tt.closesAt > 0 {
+				p.RestrictVehicle(0, Window{0, tt.closesAt})
+			}
+			if _, ok := p.Schedule(0, tt.seq); ok != tt.want {
+				t.Errorf("Schedule ok = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulePairs(t *testing.T) {
+	// A depot delivery of 6 and a pickup of 5 taken to the delivery stop,
+	// on a vehicle carrying 10
+	pickupID, deliveryID := 2, 3
+	orders := []db.Order{
+		{ID: 1, Demand: 6},
+		{ID: pickupID, Demand: 5, Kind: db.OrderKindPickup, PairOrderID: &deliveryID},
+		{ID: deliveryID, Demand: 5, Kind: db.OrderKindDelivery, PairOrderID: &pickupID},
+	}
+	p := NewProblem(orders, []db.Vehicle{{ID: 1, Capacity: 10}})
+	onLine(p, 10, 20, 30)
+
+	tests := []struct {
+		name string
+		seq  []int
+		want bool
+	}{
+		{"pickup then delivery", []int{1, 2}, true},
+		{"delivery before pickup", []int{2, 1}, false},
+		{"pickup alone", []int{1}, false},
+		{"delivery alone", []int{2}, false},
+		{"depot load dropped off first", []int{0, 1, 2}, true},
+		{"pickup on top of the depot load", []int{1, 2, 0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := p.Schedule(0, tt.seq); ok != tt.want {
+				t.Errorf("Schedule(%v) ok = %v, want %v", tt.seq, ok, tt.want)
+			}
+		})
+	}
+}
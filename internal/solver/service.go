@@ -0,0 +1,181 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"route-go/internal/db"
+	"route-go/internal/matrix"
+)
+
+// BuildSolution converts a Result into the solution_json model.
+func BuildSolution(p *Problem, res *Result) db.Solution {
+	sol := db.Solution{SchemaVersion: db.SolutionSchemaVersion, Vehicles: []db.VehicleRoute{}}
+	for v, seq := range res.Routes {
+		visits, _ := p.Schedule(v, seq)
+		vr := db.VehicleRoute{VehicleDBID: p.Vehicles[v].ID, TotalDistanceM: p.Distance(v, seq)}
+		for i, visit := range visits {
+			node := p.Nodes[visit.Node]
+			stop := db.Stop{NodeIndex: visit.Node, MinTime: visit.MinTime, MaxTime: visit.MaxTime, WaitTime: visit.Wait}
+			if visit.Node < p.NumOrders {
+				stop.OrderID = node.OrderID
+				stop.CustomerID = node.CustomerID
+				stop.CustomerName = node.CustomerName
+			}
+			if i == len(visits)-1 {
+				stop.Type = db.StopTypeEnd
+			}
+			vr.Route = append(vr.Route, stop)
+		}
+		sol.Vehicles = append(sol.Vehicles, vr)
+	}
+	return sol
+}
+
+// Service runs optimizations in-process against the database, as an
+// alternative to the Python worker listening on "route-events".
+type Service struct {
+	Repo *db.Repository
+	// Matrix defaults to matrix.Haversine when nil
+	Matrix  matrix.Provider
+	Options Options
+}
+
+// Request scopes a run. The zero value optimizes everything for today.
+type Request struct {
+	DepotID int
+	Date    time.Time
+}
+
+type RunResult struct {
+	RouteID   int
+	Objective int
+	Routed    []int
+	Dropped   []int
+	// Unassigned gives the reason for each dropped order
+	Unassigned []db.UnassignedOrder
+}
+
+// Run optimizes the orders pending for the requested day (plus those already
+// in its draft) and upserts that day's draft route, like optimize() in
+// solver.py. When scoped to a depot, only its vehicles are used and they keep
+// to its opening hours.
+func (s *Service) Run(ctx context.Context, req Request) (*RunResult, error) {
+	date := req.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	day := date.Format(db.DateLayout)
+
+	// The draft is only overwritten if nobody touches it while we solve
+	seen, err := s.Repo.GetDraftVersion(ctx, req.DepotID, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load draft route: %w", err)
+	}
+	orders, err := s.Repo.ListOrdersForOptimization(ctx, req.DepotID, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load orders: %w", err)
+	}
+	vehicles, err := s.Repo.ListVehicles(ctx, req.DepotID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vehicles: %w", err)
+	}
+	if len(vehicles) == 0 {
+		return nil, fmt.Errorf("no vehicles found")
+	}
+	// Only vehicles working that day, within their shift
+	calendars, err := s.Repo.ListAvailabilityOn(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vehicle availability: %w", err)
+	}
+	vehicles = availableVehicles(vehicles, calendars, date)
+	if len(vehicles) == 0 {
+		return nil, fmt.Errorf("no vehicles available")
+	}
+	depots, err := s.Repo.ListDepots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load depots: %w", err)
+	}
+
+	penalties, err := s.Repo.ListDropPenalties(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load drop penalties: %w", err)
+	}
+
+	p := NewProblem(orders, vehicles)
+	p.Penalties = penalties
+	ApplyDepotHours(p, vehicles, depots)
+	ApplyAvailability(p, vehicles, calendars, date)
+	provider := s.Matrix
+	if provider == nil {
+		provider = matrix.Haversine{}
+	}
+	if err := p.LoadMatrix(ctx, matrix.OnDate(provider, date)); err != nil {
+		return nil, err
+	}
+	res := Solve(p, s.Options)
+	sol := BuildSolution(p, res)
+
+	out := &RunResult{Objective: res.Objective, Routed: sol.OrderIDs()}
+	for _, n := range res.Dropped {
+		out.Dropped = append(out.Dropped, p.Nodes[n].OrderID)
+		out.Unassigned = append(out.Unassigned, db.UnassignedOrder{OrderID: p.Nodes[n].OrderID, Reason: p.UnassignedReason(n), Priority: p.Nodes[n].Priority})
+	}
+
+	out.RouteID, err = s.Repo.SaveDraftSolution(ctx, sol, out.Unassigned, req.DepotID, day, seen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save solution: %w", err)
+	}
+	return out, nil
+}
+
+// ApplyDepotHours keeps each vehicle of p, laid out from vehicles, to its
+// home depot's opening hours.
+func ApplyDepotHours(p *Problem, vehicles []db.Vehicle, depots []db.Depot) {
+	hours := make(map[int]Window, len(depots))
+	for _, d := range depots {
+		hours[d.ID] = Window{d.OpensAt, d.ClosesAt}
+	}
+	for i, v := range vehicles {
+		if v.DepotID == nil {
+			continue
+		}
+		if w, ok := hours[*v.DepotID]; ok {
+			p.RestrictVehicle(i, w)
+		}
+	}
+}
+
+// ApplyAvailability keeps each vehicle of p, laid out from vehicles, to its
+// shift on date, and marks those not working that day.
+func ApplyAvailability(p *Problem, vehicles []db.Vehicle, calendars map[int]*db.Availability, date time.Time) {
+	for i, v := range vehicles {
+		shift, working := shiftOn(calendars[v.ID], date)
+		if !working {
+			p.Vehicles[i].DayOff = true
+			continue
+		}
+		p.RestrictVehicle(i, shift)
+	}
+}
+
+// availableVehicles keeps the vehicles working on date.
+func availableVehicles(vehicles []db.Vehicle, calendars map[int]*db.Availability, date time.Time) []db.Vehicle {
+	var available []db.Vehicle
+	for _, v := range vehicles {
+		if _, working := shiftOn(calendars[v.ID], date); working {
+			available = append(available, v)
+		}
+	}
+	return available
+}
+
+// shiftOn is when a vehicle with calendar a, nil for none, works on date.
+func shiftOn(a *db.Availability, date time.Time) (Window, bool) {
+	if a == nil {
+		return Window{DayStart, DayEnd}, true
+	}
+	start, end, working := a.HoursOn(date)
+	return Window{start, end}, working
+}
@@ -0,0 +1,314 @@
+package solver
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type Options struct {
+	// TimeLimit bounds the local search phase, like search_parameters.time_limit in solver.py
+	TimeLimit time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{TimeLimit: 30 * time.Second}
+}
+
+// Result holds the order node sequence for each vehicle (same index as
+// Problem.Vehicles) and the orders that could not be served.
+type Result struct {
+	Routes    [][]int
+	Dropped   []int
+	Objective int
+}
+
+// Solve builds routes with a parallel cheapest insertion heuristic, taking
+// orders by descending drop penalty, and then improves them with relocate,
+// exchange and 2-opt moves until no move helps or the time limit is reached.
+func Solve(p *Problem, opts Options) *Result {
+	if opts.TimeLimit <= 0 {
+		opts = DefaultOptions()
+	}
+	s := &search{p: p, routes: make([][]int, len(p.Vehicles)), deadline: time.Now().Add(opts.TimeLimit)}
+
+	var pending []int
+	for i := 0; i < p.NumOrders; i++ {
+		if p.Unit(i)[0] == i {
+			pending = append(pending, i)
+		}
+	}
+	dropped := s.insertByPenalty(pending)
+
+	for !s.expired() {
+		improved := s.relocate() || s.exchange() || s.twoOpt()
+		if !improved {
+			break
+		}
+		if len(dropped) > 0 {
+			dropped = s.insertByPenalty(dropped)
+		}
+	}
+
+	var all []int
+	for _, n := range dropped {
+		all = append(all, p.Unit(n)...)
+	}
+	return &Result{Routes: s.routes, Dropped: all, Objective: s.objective(all)}
+}
+
+type search struct {
+	p        *Problem
+	routes   [][]int
+	deadline time.Time
+}
+
+// insertion puts an order at pos and, for a pickup, its delivery at pos2 of
+// the resulting sequence.
+type insertion struct {
+	vehicle int
+	pos     int
+	pos2    int
+	delta   int
+}
+
+func (s *search) expired() bool {
+	return time.Now().After(s.deadline)
+}
+
+func (s *search) objective(dropped []int) int {
+	total := 0
+	for _, n := range dropped {
+		total += s.p.Penalty(n)
+	}
+	for v, seq := range s.routes {
+		total += s.routeCost(v, seq)
+	}
+	return total
+}
+
+func (s *search) routeCost(v int, seq []int) int {
+	if len(seq) == 0 {
+		return 0
+	}
+	return s.p.Distance(v, seq) + VehicleFixedCost
+}
+
+func (s *search) feasible(v int, seq []int) bool {
+	_, ok := s.p.Schedule(v, seq)
+	return ok
+}
+
+// insertByPenalty runs insertAll on groups of equally penalized orders (pairs
+// counting both halves), costliest first, so that cheap orders never take
+// the room an expensive one needs. It returns the orders that fit nowhere.
+func (s *search) insertByPenalty(nodes []int) []int {
+	penalty := func(n int) int {
+		total := 0
+		for _, m := range s.p.Unit(n) {
+			total += s.p.Penalty(m)
+		}
+		return total
+	}
+	sorted := append([]int(nil), nodes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return penalty(sorted[i]) > penalty(sorted[j]) })
+
+	var left []int
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && penalty(sorted[end]) == penalty(sorted[start]) {
+			end++
+		}
+		left = append(left, s.insertAll(sorted[start:end])...)
+		start = end
+	}
+	return left
+}
+
+// insertAll repeatedly inserts the globally cheapest feasible (order, position)
+// pair and returns the orders that fit nowhere. A paired pickup is inserted
+// together with its delivery, which must not be listed in nodes itself.
+func (s *search) insertAll(nodes []int) []int {
+	best := make(map[int][]insertion, len(nodes))
+	for _, n := range nodes {
+		best[n] = make([]insertion, len(s.routes))
+		for v := range s.routes {
+			best[n][v] = s.bestInsertion(n, v)
+		}
+	}
+
+	for len(best) > 0 {
+		chosen, ins := -1, insertion{delta: math.MaxInt}
+		for _, n := range nodes {
+			options, ok := best[n]
+			if !ok {
+				continue
+			}
+			for _, opt := range options {
+				if opt.delta < ins.delta {
+					chosen, ins = n, opt
+				}
+			}
+		}
+		if chosen < 0 {
+			break
+		}
+
+		seq := insertAt(s.routes[ins.vehicle], ins.pos, chosen)
+		if unit := s.p.Unit(chosen); len(unit) > 1 {
+			seq = insertAt(seq, ins.pos2, unit[1])
+		}
+		s.routes[ins.vehicle] = seq
+		delete(best, chosen)
+		for n := range best {
+			best[n][ins.vehicle] = s.bestInsertion(n, ins.vehicle)
+		}
+	}
+
+	var left []int
+	for _, n := range nodes {
+		if _, ok := best[n]; ok {
+			left = append(left, n)
+		}
+	}
+	return left
+}
+
+func (s *search) bestInsertion(node, v int) insertion {
+	best := insertion{vehicle: v, delta: math.MaxInt}
+	seq := s.routes[v]
+	unit := s.p.Unit(node)
+	for _, n := range unit {
+		if !s.p.CanServe(v, n) {
+			return best
+		}
+	}
+	if !s.p.Fits(v, s.p.PeakLoad(unit)) || !s.p.Fits(v, s.p.Load(append(unit, seq...))) {
+		return best
+	}
+	base := s.routeCost(v, seq)
+	for pos := 0; pos <= len(seq); pos++ {
+		candidate := insertAt(seq, pos, node)
+		if len(unit) == 1 {
+			delta := s.routeCost(v, candidate) - base
+			if delta < best.delta && s.feasible(v, candidate) {
+				best.pos, best.delta = pos, delta
+			}
+			continue
+		}
+		for pos2 := pos + 1; pos2 <= len(candidate); pos2++ {
+			paired := insertAt(candidate, pos2, unit[1])
+			delta := s.routeCost(v, paired) - base
+			if delta < best.delta && s.feasible(v, paired) {
+				best.pos, best.pos2, best.delta = pos, pos2, delta
+			}
+		}
+	}
+	return best
+}
+
+// relocate moves a single order to another position, possibly on another vehicle.
+func (s *search) relocate() bool {
+	for a := range s.routes {
+		for i := 0; i < len(s.routes[a]); i++ {
+			node := s.routes[a][i]
+			removed := removeAt(s.routes[a], i)
+			gain := s.routeCost(a, s.routes[a]) - s.routeCost(a, removed)
+
+			for b := range s.routes {
+				target := s.routes[b]
+				if b == a {
+					target = removed
+				}
+				base := s.routeCost(b, target)
+				for j := 0; j <= len(target); j++ {
+					if b == a && j == i {
+						continue
+					}
+					candidate := insertAt(target, j, node)
+					if s.routeCost(b, candidate)-base >= gain {
+						continue
+					}
+					if b == a {
+						if s.feasible(a, candidate) {
+							s.routes[a] = candidate
+							return true
+						}
+						continue
+					}
+					if s.feasible(b, candidate) && s.feasible(a, removed) {
+						s.routes[a], s.routes[b] = removed, candidate
+						return true
+					}
+				}
+			}
+			if s.expired() {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// exchange swaps two orders served by different vehicles.
+func (s *search) exchange() bool {
+	for a := range s.routes {
+		for b := a + 1; b < len(s.routes); b++ {
+			base := s.routeCost(a, s.routes[a]) + s.routeCost(b, s.routes[b])
+			for i := range s.routes[a] {
+				for j := range s.routes[b] {
+					ra := append([]int(nil), s.routes[a]...)
+					rb := append([]int(nil), s.routes[b]...)
+					ra[i], rb[j] = rb[j], ra[i]
+					if s.routeCost(a, ra)+s.routeCost(b, rb) >= base {
+						continue
+					}
+					if s.feasible(a, ra) && s.feasible(b, rb) {
+						s.routes[a], s.routes[b] = ra, rb
+						return true
+					}
+				}
+			}
+			if s.expired() {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// twoOpt reverses a segment inside a single route.
+func (s *search) twoOpt() bool {
+	for v, seq := range s.routes {
+		base := s.routeCost(v, seq)
+		for i := 0; i < len(seq)-1; i++ {
+			for j := i + 1; j < len(seq); j++ {
+				candidate := append([]int(nil), seq...)
+				for l, r := i, j; l < r; l, r = l+1, r-1 {
+					candidate[l], candidate[r] = candidate[r], candidate[l]
+				}
+				if s.routeCost(v, candidate) < base && s.feasible(v, candidate) {
+					s.routes[v] = candidate
+					return true
+				}
+			}
+		}
+		if s.expired() {
+			return false
+		}
+	}
+	return false
+}
+
+func insertAt(seq []int, pos, node int) []int {
+	out := make([]int, 0, len(seq)+1)
+	out = append(out, seq[:pos]...)
+	out = append(out, node)
+	return append(out, seq[pos:]...)
+}
+
+func removeAt(seq []int, pos int) []int {
+	out := make([]int, 0, len(seq)-1)
+	out = append(out, seq[:pos]...)
+	return append(out, seq[pos+1:]...)
+}
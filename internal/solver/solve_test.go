@@ -0,0 +1,133 @@
+package solver
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"route-go/internal/db"
+)
+
+var fastOptions = Options{TimeLimit: time.Second}
+
+// routedOn maps each routed order ID to the ID of its vehicle.
+func routedOn(p *Problem, res *Result) map[int]int {
+	routed := make(map[int]int)
+	for v, seq := range res.Routes {
+		for _, n := range seq {
+			routed[p.Nodes[n].OrderID] = p.Vehicles[v].ID
+		}
+	}
+	return routed
+}
+
+func droppedIDs(p *Problem, res *Result) []int {
+	ids := []int{}
+	for _, n := range res.Dropped {
+		ids = append(ids, p.Nodes[n].OrderID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestSolveCapacity(t *testing.T) {
+	orders := []db.Order{{ID: 1, Demand: 6}, {ID: 2, Demand: 6}}
+	tests := []struct {
+		name        string
+		vehicles    []db.Vehicle
+		wantDropped []int
+	}{
+		{"one vehicle carries one order", []db.Vehicle{{ID: 1, Capacity: 10}}, []int{2}},
+		{"two vehicles carry both", []db.Vehicle{{ID: 1, Capacity: 10}, {ID: 2, Capacity: 10}}, []int{}},
+		{"one vehicle large enough", []db.Vehicle{{ID: 1, Capacity: 12}}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProblem(orders, tt.vehicles)
+			onLine(p, 10, 20)
+			res := Solve(p, fastOptions)
+			if got := droppedIDs(p, res); !reflect.DeepEqual(got, tt.wantDropped) {
+				t.Errorf("dropped %v, want %v", got, tt.wantDropped)
+			}
+			for v, seq := range res.Routes {
+				if _, ok := p.Schedule(v, seq); !ok {
+					t.Errorf("vehicle %d got infeasible route %v", p.Vehicles[v].ID, seq)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveKeepsCostlierPriority(t *testing.T) {
+	orders := []db.Order{
+		{ID: 1, Demand: 6, Priority: db.PriorityLow},
+		{ID: 2, Demand: 6, Priority: db.PriorityMandatory},
+	}
+	p := NewProblem(orders, []db.Vehicle{{ID: 1, Capacity: 10}})
+	onLine(p, 10, 20)
+	res := Solve(p, fastOptions)
+	if got := droppedIDs(p, res); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("dropped %v, want the low priority order 1", got)
+	}
+}
+
+func TestSolvePairs(t *testing.T) {
+	pickupID, deliveryID := 1, 2
+	pair := []db.Order{
+		{ID: pickupID, Demand: 5, Kind: db.OrderKindPickup, PairOrderID: &deliveryID},
+		{ID: deliveryID, Demand: 5, Kind: db.OrderKindDelivery, PairOrderID: &pickupID},
+	}
+
+	t.Run("same vehicle, pickup first", func(t *testing.T) {
+		// The delivery is on the way to the pickup: visiting in
+		// distance order would break the pair
+		p := NewProblem(pair, []db.Vehicle{{ID: 1, Capacity: 10}, {ID: 2, Capacity: 10}})
+		onLine(p, 20, 10)
+		res := Solve(p, fastOptions)
+		if len(res.Dropped) > 0 {
+			t.Fatalf("dropped %v, want the pair routed", droppedIDs(p, res))
+		}
+		routed := routedOn(p, res)
+		if routed[pickupID] != routed[deliveryID] {
+			t.Fatalf("pickup on vehicle %d, delivery on vehicle %d", routed[pickupID], routed[deliveryID])
+		}
+		for _, seq := range res.Routes {
+			if len(seq) > 0 && !reflect.DeepEqual(seq, []int{0, 1}) {
+				t.Errorf("route %v, want pickup (0) then delivery (1)", seq)
+			}
+		}
+	})
+
+	t.Run("dropped together", func(t *testing.T) {
+		p := NewProblem(pair, []db.Vehicle{{ID: 1, Capacity: 4}})
+		onLine(p, 20, 10)
+		res := Solve(p, fastOptions)
+		if got := droppedIDs(p, res); !reflect.DeepEqual(got, []int{pickupID, deliveryID}) {
+			t.Errorf("dropped %v, want both halves of the pair", got)
+		}
+	})
+}
+
+func TestSolveDepots(t *testing.T) {
+	orders := []db.Order{
+		{ID: 1, Demand: 1, DepotID: depot(1)},
+		{ID: 2, Demand: 1, DepotID: depot(2)},
+		{ID: 3, Demand: 1, DepotID: depot(3)},
+	}
+	vehicles := []db.Vehicle{
+		{ID: 1, Capacity: 10, DepotID: depot(1)},
+		{ID: 2, Capacity: 10, DepotID: depot(2)},
+	}
+	p := NewProblem(orders, vehicles)
+	onLine(p, 10, 10, 10)
+	res := Solve(p, fastOptions)
+
+	routed := routedOn(p, res)
+	if routed[1] != 1 || routed[2] != 2 {
+		t.Errorf("orders routed on %v, want each on its depot's vehicle", routed)
+	}
+	if got := droppedIDs(p, res); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("dropped %v, want order 3 whose depot has no vehicle", got)
+	}
+}
@@ -0,0 +1,212 @@
+package validation
+
+import (
+	"fmt"
+
+	"route-go/internal/db"
+	"route-go/internal/solver"
+)
+
+// Errors break data integrity and are never saved; warnings describe a
+// route that can be driven but breaks a constraint, and can be forced.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+const (
+	CodeUnknownVehicle   = "unknown_vehicle"
+	CodeDuplicateVehicle = "duplicate_vehicle"
+	CodeVehicleDayOff    = "vehicle_day_off"
+	CodeUnknownOrder     = "unknown_order"
+	CodeDuplicateOrder   = "duplicate_order"
+	CodeOrderInOther     = "order_in_confirmed_route"
+	CodeWrongDepot       = "wrong_depot"
+	CodeMissingSkill     = "missing_skill"
+	CodePairSplit        = "pair_split"
+	CodePairOrder        = "delivery_before_pickup"
+	CodeMandatory        = "mandatory_unassigned"
+	CodeCapacity         = "capacity_exceeded"
+	CodeTimeWindow       = "time_window_violated"
+)
+
+type Violation struct {
+	Code        string `json:"code"`
+	Severity    string `json:"severity"`
+	VehicleDBID int    `json:"vehicle_db_id,omitempty"`
+	OrderID     int    `json:"order_id,omitempty"`
+	Message     string `json:"message"`
+}
+
+// Validate checks a solution laid out on plan. confirmedElsewhere maps order
+// IDs to the other confirmed route they already belong to. The plan's
+// problem needs its matrix loaded for the time window checks.
+func Validate(sol db.Solution, plan *solver.Plan, confirmedElsewhere map[int]int) []Violation {
+	violations := []Violation{}
+	p := plan.Problem
+
+	seenVehicles := make(map[int]bool)
+	seenOrders := make(map[int]int) // order -> vehicle
+	for i, vr := range sol.Vehicles {
+		pr := plan.Routes[i]
+
+		if pr.Vehicle < 0 {
+			violations = append(violations, Violation{
+				Code: CodeUnknownVehicle, Severity: SeverityError, VehicleDBID: vr.VehicleDBID,
+				Message: fmt.Sprintf("vehicle %d does not exist", vr.VehicleDBID),
+			})
+		} else if seenVehicles[vr.VehicleDBID] {
+			violations = append(violations, Violation{
+				Code: CodeDuplicateVehicle, Severity: SeverityError, VehicleDBID: vr.VehicleDBID,
+				Message: fmt.Sprintf("vehicle %d has more than one route", vr.VehicleDBID),
+			})
+		}
+		seenVehicles[vr.VehicleDBID] = true
+
+		for _, id := range vr.OrderIDs() {
+			if prev, ok := seenOrders[id]; ok {
+				violations = append(violations, Violation{
+					Code: CodeDuplicateOrder, Severity: SeverityError, VehicleDBID: vr.VehicleDBID, OrderID: id,
+					Message: fmt.Sprintf("order %d is already served by vehicle %d", id, prev),
+				})
+				continue
+			}
+			seenOrders[id] = vr.VehicleDBID
+			if routeID, ok := confirmedElsewhere[id]; ok {
+				violations = append(violations, Violation{
+					Code: CodeOrderInOther, Severity: SeverityError, VehicleDBID: vr.VehicleDBID, OrderID: id,
+					Message: fmt.Sprintf("order %d belongs to confirmed route %d", id, routeID),
+				})
+			}
+		}
+		for _, id := range pr.Missing {
+			violations = append(violations, Violation{
+				Code: CodeUnknownOrder, Severity: SeverityError, VehicleDBID: vr.VehicleDBID, OrderID: id,
+				Message: fmt.Sprintf("order %d does not exist", id),
+			})
+		}
+
+		if pr.Vehicle < 0 {
+			continue
+		}
+		veh := p.Vehicles[pr.Vehicle]
+		if veh.DayOff && len(pr.Seq) > 0 {
+			violations = append(violations, Violation{
+				Code: CodeVehicleDayOff, Severity: SeverityError, VehicleDBID: vr.VehicleDBID,
+				Message: fmt.Sprintf("vehicle %d does not work on the route's day", vr.VehicleDBID),
+			})
+		}
+		for _, n := range pr.Seq {
+			if !p.InDepot(pr.Vehicle, n) {
+				violations = append(violations, Violation{
+					Code: CodeWrongDepot, Severity: SeverityError, VehicleDBID: vr.VehicleDBID, OrderID: p.Nodes[n].OrderID,
+					Message: fmt.Sprintf("order %d belongs to depot %d, which vehicle %d is not from", p.Nodes[n].OrderID, p.Nodes[n].DepotID, vr.VehicleDBID),
+				})
+			}
+			for _, skill := range p.MissingSkills(pr.Vehicle, n) {
+				violations = append(violations, Violation{
+					Code: CodeMissingSkill, Severity: SeverityError, VehicleDBID: vr.VehicleDBID, OrderID: p.Nodes[n].OrderID,
+					Message: fmt.Sprintf("order %d requires skill %q, which vehicle %d lacks", p.Nodes[n].OrderID, skill, vr.VehicleDBID),
+				})
+			}
+		}
+		for d, load := range p.PeakLoad(pr.Seq) {
+			if load <= veh.Capacity[d] {
+				continue
+			}
+			violations = append(violations, Violation{
+				Code: CodeCapacity, Severity: SeverityWarning, VehicleDBID: vr.VehicleDBID,
+				Message: fmt.Sprintf("%s load %d exceeds capacity %d", p.Dimensions[d], load, veh.Capacity[d]),
+			})
+		}
+		for _, visit := range p.Timeline(pr.Vehicle, pr.Seq) {
+			if visit.Late <= 0 {
+				continue
+			}
+			node := p.Nodes[visit.Node]
+			msg := fmt.Sprintf("arrives at %s, %d min after the time window closes", FormatMinutes(visit.Arrival), visit.Late)
+			if visit.Node == veh.End {
+				msg = fmt.Sprintf("returns to the depot at %s, %d min after it closes", FormatMinutes(visit.Arrival), visit.Late)
+			}
+			violations = append(violations, Violation{
+				Code: CodeTimeWindow, Severity: SeverityWarning, VehicleDBID: vr.VehicleDBID, OrderID: node.OrderID,
+				Message: msg,
+			})
+		}
+	}
+	return append(violations, pairViolations(plan, seenOrders)...)
+}
+
+// pairViolations checks that both orders of every routed pickup-and-delivery
+// pair are served by the same vehicle, pickup first. served maps order IDs
+// to their vehicle.
+func pairViolations(plan *solver.Plan, served map[int]int) []Violation {
+	var violations []Violation
+	p := plan.Problem
+	for _, pr := range plan.Routes {
+		if pr.Vehicle < 0 {
+			continue
+		}
+		broken := make(map[int]bool)
+		for _, n := range p.BrokenPairs(pr.Seq) {
+			broken[n] = true
+		}
+		for _, n := range pr.Seq {
+			node := p.Nodes[n]
+			if node.PairOrderID == 0 {
+				continue
+			}
+			vehicle, ok := served[node.PairOrderID]
+			switch {
+			case !ok:
+				violations = append(violations, Violation{
+					Code: CodePairSplit, Severity: SeverityError, VehicleDBID: pr.VehicleDBID, OrderID: node.OrderID,
+					Message: fmt.Sprintf("order %d is routed without its pair, order %d", node.OrderID, node.PairOrderID),
+				})
+			case vehicle != pr.VehicleDBID:
+				violations = append(violations, Violation{
+					Code: CodePairSplit, Severity: SeverityError, VehicleDBID: pr.VehicleDBID, OrderID: node.OrderID,
+					Message: fmt.Sprintf("order %d and its pair, order %d, are served by different vehicles", node.OrderID, node.PairOrderID),
+				})
+			case node.Pickup && broken[n]:
+				violations = append(violations, Violation{
+					Code: CodePairOrder, Severity: SeverityError, VehicleDBID: pr.VehicleDBID, OrderID: node.PairOrderID,
+					Message: fmt.Sprintf("order %d is delivered before its pickup, order %d", node.PairOrderID, node.OrderID),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// Mandatory turns the mandatory orders among unassigned into errors: they
+// may be missing from a draft, but never from a route being confirmed or by
+// being taken out of it.
+func Mandatory(unassigned []db.UnassignedOrder) []Violation {
+	violations := []Violation{}
+	for _, u := range unassigned {
+		if !u.IsMandatory() {
+			continue
+		}
+		violations = append(violations, Violation{
+			Code: CodeMandatory, Severity: SeverityError, OrderID: u.OrderID,
+			Message: fmt.Sprintf("mandatory order %d is not routed: %s", u.OrderID, u.Reason),
+		})
+	}
+	return violations
+}
+
+// HasErrors reports whether any violation cannot be forced.
+func HasErrors(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatMinutes renders minutes from midnight as HH:MM.
+func FormatMinutes(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
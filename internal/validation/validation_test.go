@@ -0,0 +1,142 @@
+package validation
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"route-go/internal/db"
+	"route-go/internal/solver"
+)
+
+// onLine lays the orders of p out on a line at the given positions, one per
+// order, with every vehicle starting and ending at 0. Each unit is 1 km and
+// 1 minute of driving.
+func onLine(p *solver.Problem, orderPos ...int) {
+	pos := make([]int, len(p.Nodes))
+	copy(pos, orderPos)
+	p.Dist = make([][]int, len(pos))
+	p.Time = make([][]int, len(pos))
+	for i := range pos {
+		p.Dist[i] = make([]int, len(pos))
+		p.Time[i] = make([]int, len(pos))
+		for j := range pos {
+			d := max(pos[i]-pos[j], pos[j]-pos[i])
+			p.Dist[i][j] = 1000 * d
+			p.Time[i][j] = d
+		}
+	}
+}
+
+// route is the vehicle route serving orderIDs in turn.
+func route(vehicleID int, orderIDs ...int) db.VehicleRoute {
+	vr := db.VehicleRoute{VehicleDBID: vehicleID}
+	for _, id := range orderIDs {
+		vr.Route = append(vr.Route, db.Stop{OrderID: id})
+	}
+	vr.Route = append(vr.Route, db.Stop{Type: db.StopTypeEnd})
+	return vr
+}
+
+func codes(violations []Violation) []string {
+	out := []string{}
+	for _, v := range violations {
+		out = append(out, v.Code)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func depot(id int) *int {
+	return &id
+}
+
+func TestValidate(t *testing.T) {
+	pickupID, deliveryID := 3, 4
+	orders := []db.Order{
+		{ID: 1, Demand: 4, DepotID: depot(1)},
+		{ID: 2, Demand: 4, TimeWindows: []any{0.0, 5.0}, RequiredSkills: []string{"refrigerated"}},
+		{ID: pickupID, Demand: 2, Kind: db.OrderKindPickup, PairOrderID: &deliveryID},
+		{ID: deliveryID, Demand: 2, Kind: db.OrderKindDelivery, PairOrderID: &pickupID},
+	}
+	vehicles := []db.Vehicle{
+		{ID: 1, Capacity: 10, DepotID: depot(1), Skills: []string{"refrigerated"}},
+		{ID: 2, Capacity: 6, DepotID: depot(2)},
+		{ID: 3, Capacity: 10, DepotID: depot(1)},
+	}
+
+	tests := []struct {
+		name      string
+		routes    []db.VehicleRoute
+		confirmed map[int]int
+		dayOff    bool // vehicle 1 does not work that day
+		want      []string
+	}{
+		{"valid", []db.VehicleRoute{route(1, 3, 1, 4)}, nil, false, []string{}},
+		{"unknown vehicle", []db.VehicleRoute{route(9)}, nil, false, []string{CodeUnknownVehicle}},
+		{"duplicate vehicle", []db.VehicleRoute{route(1, 1), route(1)}, nil, false, []string{CodeDuplicateVehicle}},
+		{"vehicle day off", []db.VehicleRoute{route(1, 1)}, nil, true, []string{CodeVehicleDayOff}},
+		{"unknown order", []db.VehicleRoute{route(1, 99)}, nil, false, []string{CodeUnknownOrder}},
+		{"duplicate order", []db.VehicleRoute{route(1, 1), route(3, 1)}, nil, false, []string{CodeDuplicateOrder}},
+		{"order in confirmed route", []db.VehicleRoute{route(1, 1)}, map[int]int{1: 7}, false, []string{CodeOrderInOther}},
+		{"wrong depot", []db.VehicleRoute{route(2, 1)}, nil, false, []string{CodeWrongDepot}},
+		// Vehicle 2 has no skill and comes too late for order 2's window
+		{"missing skill", []db.VehicleRoute{route(2, 2)}, nil, false, []string{CodeMissingSkill, CodeTimeWindow}},
+		{"pair split over vehicles", []db.VehicleRoute{route(1, 3), route(2, 4)}, nil, false, []string{CodePairSplit, CodePairSplit}},
+		{"pair half alone", []db.VehicleRoute{route(1, 3)}, nil, false, []string{CodePairSplit}},
+		{"delivery before pickup", []db.VehicleRoute{route(1, 4, 3)}, nil, false, []string{CodePairOrder}},
+		{"time window violated", []db.VehicleRoute{route(1, 1, 2)}, nil, false, []string{CodeTimeWindow}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sol := db.Solution{Vehicles: tt.routes}
+			plan := solver.NewPlan(sol, orders, vehicles)
+			onLine(plan.Problem, 10, 10, 20, 30)
+			plan.Problem.Vehicles[0].DayOff = tt.dayOff
+			got := Validate(sol, plan, tt.confirmed)
+			if !reflect.DeepEqual(codes(got), tt.want) {
+				t.Errorf("Validate codes = %v, want %v (%+v)", codes(got), tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateCapacity(t *testing.T) {
+	orders := []db.Order{{ID: 1, Demand: 4}, {ID: 2, Demand: 4}}
+	vehicles := []db.Vehicle{{ID: 1, Capacity: 6}}
+	sol := db.Solution{Vehicles: []db.VehicleRoute{route(1, 1, 2)}}
+	plan := solver.NewPlan(sol, orders, vehicles)
+	onLine(plan.Problem, 10, 20)
+
+	got := Validate(sol, plan, nil)
+	if !reflect.DeepEqual(codes(got), []string{CodeCapacity}) {
+		t.Fatalf("Validate codes = %v, want [%s]", codes(got), CodeCapacity)
+	}
+	if got[0].Severity != SeverityWarning || HasErrors(got) {
+		t.Errorf("capacity is %s, want a warning that can be forced", got[0].Severity)
+	}
+}
+
+func TestMandatory(t *testing.T) {
+	unassigned := []db.UnassignedOrder{
+		{OrderID: 1, Reason: "no vehicles available", Priority: db.PriorityMandatory},
+		{OrderID: 2, Reason: "no vehicles available", Priority: db.PriorityHigh},
+		{OrderID: 3, Reason: "no vehicles available"},
+	}
+	got := Mandatory(unassigned)
+	if len(got) != 1 || got[0].Code != CodeMandatory || got[0].OrderID != 1 {
+		t.Fatalf("Mandatory = %+v, want one %s for order 1", got, CodeMandatory)
+	}
+	if !HasErrors(got) {
+		t.Error("HasErrors = false, want a mandatory order to be an error")
+	}
+}
+
+func TestFormatMinutes(t *testing.T) {
+	tests := map[int]string{0: "00:00", 65: "01:05", 1439: "23:59"}
+	for m, want := range tests {
+		if got := FormatMinutes(m); got != want {
+			t.Errorf("FormatMinutes(%d) = %q, want %q", m, got, want)
+		}
+	}
+}
@@ -21,8 +21,9 @@ def migrate_and_seed():
     # 1. Alter Table
     print("Migrating schema...")
     try:
-        cursor.execute("ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS end_lat FLOAT DEFAULT 0.0;")
-        cursor.execute("ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS end_lon FLOAT DEFAULT 0.0;")
+        # NULL end location = back to start (see internal/db/schema.sql)
+        cursor.execute("ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS end_lat FLOAT;")
+        cursor.execute("ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS end_lon FLOAT;")
         # Ensure start columns exist (though schema.sql says they do)
         cursor.execute("ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS start_lat FLOAT DEFAULT 0.0;")
         cursor.execute("ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS start_lon FLOAT DEFAULT 0.0;")
@@ -1,6 +1,7 @@
 import os
 import json
 import time
+import datetime
 import psycopg2
 from ortools.constraint_solver import routing_enums_pb2
 from ortools.constraint_solver import pywrapcp
@@ -19,6 +20,14 @@ DB_PASS = os.getenv('DB_PASS', 'password')
 # Velocidade média urbana em metros por minuto (30 km/h = 500 m/min)
 AVERAGE_SPEED_M_PER_MIN = 500
 
+# Capacity of a vehicle in a dimension it does not declare
+UNLIMITED_CAPACITY = 2**31 - 1
+
+# Cost of leaving out an order, by priority, when drop_penalties has no row
+# for it (see db.DefaultDropPenalties in Go)
+DROP_PENALTY = 1_000_000
+DEFAULT_DROP_PENALTIES = {'mandatory': 100_000_000, 'high': 5_000_000, 'normal': DROP_PENALTY, 'low': 200_000}
+
 def haversine_distance(lat1, lon1, lat2, lon2):
     """
     Calcula a distância real em metros entre dois pontos usando a fórmula de Haversine.
@@ -65,20 +74,45 @@ def cluster_locations(locations, n_clusters=None):
     return labels.tolist()
 
 
-def create_data_model(cursor):
-    """Stores the data for the problem."""
+def vehicle_shift(has_shifts, shift_start, shift_end, available, exception_start, exception_end):
+    """
+    Resolves a vehicle's working hours on the planned day, like db.Availability.HoursOn.
+    Returns (start, end) or None on a day off.
+    """
+    start, end = 0, 1440
+    working = not has_shifts
+    if shift_start is not None:
+        start, end, working = shift_start, shift_end, True
+    if available is not None:
+        if not available:
+            return None
+        working = True
+        if exception_start is not None:
+            start = exception_start
+        if exception_end is not None:
+            end = exception_end
+    return (start, end) if working else None
+
+
+def create_data_model(cursor, depot_id=None, plan_date=None):
+    """Stores the data for the problem on plan_date (YYYY-MM-DD, today when None). With a depot_id only that depot's vehicles are used."""
+    plan_date = plan_date or datetime.date.today().isoformat()
     # We allow re-optimization even if confirmed, to handle late updates ("changes after confirmation").
 
     data = {}
 
-    # 1. Fetch Orders (Pending or already assigned to today's route)
+    # 1. Fetch Orders (Pending for the day or undated, or already in that day's draft)
+    # Scoped to a depot: its own draft, and orders tied to it or to no depot
     cursor.execute("""
-        SELECT id, lat, lon, demand, time_windows, service_duration, customer_id, customer_name 
+        SELECT id, lat, lon, demand, time_windows, service_duration, customer_id, customer_name, quantities, required_skills,
+               kind, pair_order_id, priority, depot_id
         FROM orders 
-        WHERE status = 'pending' 
-           OR route_id IN (SELECT id FROM routes WHERE route_date = CURRENT_DATE AND status = 'draft')
+        WHERE ((status = 'pending' AND (delivery_date IS NULL OR delivery_date = %(date)s::date))
+           OR route_id IN (SELECT id FROM routes WHERE route_date = %(date)s::date AND status = 'draft'
+                           AND (%(depot)s IS NULL OR depot_id = %(depot)s)))
+          AND (%(depot)s IS NULL OR depot_id IS NULL OR depot_id = %(depot)s)
         ORDER BY id
-    """)
+    """, {"depot": depot_id, "date": plan_date})
     orders = cursor.fetchall()
     
     _locations = []
@@ -87,12 +121,19 @@ def create_data_model(cursor):
     _service_times = []
     _ids = [] 
     _order_metadata = {} # Map node_index -> {customer_id, customer_name}
+    _pickups = set() # Order nodes loaded on site rather than at the depot
+    _pair_of = {} # order id -> paired order id
     
     # --- PROCESS ORDERS ---
     for i, o in enumerate(orders):
-        oid, lat, lon, demand, tw_json, duration, cust_id, cust_name = o
+        oid, lat, lon, demand, tw_json, duration, cust_id, cust_name, quantities, required_skills, kind, pair_order_id, priority, order_depot = o
+        if kind == 'pickup':
+            _pickups.add(i)
+        if pair_order_id is not None:
+            _pair_of[oid] = pair_order_id
         _locations.append((lat, lon))
-        _demands.append(demand)
+        # "weight" is the demand column, other dimensions live in quantities
+        _demands.append(dict(quantities or {}, weight=demand))
         _ids.append(oid)
         _service_times.append(duration)
         
@@ -101,7 +142,10 @@ def create_data_model(cursor):
             "customer_id": cust_id,
             "customer_name": cust_name,
             "order_id": oid,
-            "type": "order"
+            "type": "order",
+            "required_skills": required_skills or [],
+            "priority": priority,
+            "depot_id": order_depot  # Only this depot's vehicles may serve it, any when None
         }
         
         # Parse time windows. Expected JSON: [[start, end], ...] or [{"start": 480, "end": 660}, ...]
@@ -136,39 +180,75 @@ def create_data_model(cursor):
 
     # --- PROCESS VEHICLES ---
     data['vehicle_capacities'] = []
+    data['vehicle_skills'] = []
+    data['vehicle_depots'] = []
     data['vehicle_ids'] = []
     data['starts'] = []
     data['ends'] = []
+    data['open_ends'] = []
     
-    # Columns expected: id, capacity, start_lat, start_lon, end_lat, end_lon
-    # Assuming the migration script has run and columns exist.
-    cursor.execute("SELECT id, capacity, start_lat, start_lon, end_lat, end_lon FROM vehicles")
-    vehicles = cursor.fetchall()
+    # Vehicles without an end location come back to where they started.
+    # Open routes end at the last stop (see the matrices in optimize()).
+    # Depot hours bound when a vehicle may leave and must be back (whole day without a depot),
+    # and so does the planned day's shift (see vehicle_shifts / vehicle_availability_exceptions)
+    cursor.execute("""
+        SELECT v.id, v.capacity, v.start_lat, v.start_lon,
+               COALESCE(v.end_lat, v.start_lat), COALESCE(v.end_lon, v.start_lon), v.route_mode = 'open',
+               COALESCE(d.opens_at, 0), COALESCE(d.closes_at, 1440),
+               EXISTS (SELECT 1 FROM vehicle_shifts x WHERE x.vehicle_id = v.id), s.starts_at, s.ends_at,
+               e.available, e.starts_at, e.ends_at,
+               v.capacities, v.skills, v.depot_id
+        FROM vehicles v
+        LEFT JOIN depots d ON d.id = v.depot_id
+        LEFT JOIN vehicle_shifts s ON s.vehicle_id = v.id AND s.weekday = EXTRACT(DOW FROM %(date)s::date)
+        LEFT JOIN vehicle_availability_exceptions e ON e.vehicle_id = v.id AND e.date = %(date)s::date
+        WHERE v.active AND (%(depot)s IS NULL OR v.depot_id = %(depot)s)
+        ORDER BY v.id
+    """, {"depot": depot_id, "date": plan_date})
+    vehicles = []
+    for row in cursor.fetchall():
+        shift = vehicle_shift(*row[9:15])
+        if shift is None:
+            continue  # Day off
+        vid, cap, start_lat, start_lon, end_lat, end_lon, is_open, opens_at, closes_at = row[:9]
+        shift_start = max(opens_at, shift[0])
+        shift_end = max(min(closes_at, shift[1]), shift_start)
+        capacities = dict(row[15] or {}, weight=cap)
+        vehicles.append((vid, capacities, start_lat, start_lon, end_lat, end_lon, is_open, shift_start, shift_end, set(row[16] or []), row[17]))
     
     for v in vehicles:
-        vid, cap, start_lat, start_lon, end_lat, end_lon = v
-        data['vehicle_capacities'].append(cap)
+        vid, capacities, start_lat, start_lon, end_lat, end_lon, is_open, opens_at, closes_at, skills, vehicle_depot = v
+        data['vehicle_skills'].append(skills)
+        data['vehicle_depots'].append(vehicle_depot)
+        data['vehicle_capacities'].append(capacities)
         data['vehicle_ids'].append(vid)
         
         # Add START node
         start_idx = len(_locations)
         _locations.append((start_lat, start_lon))
-        _demands.append(0) # Depots have no demand
+        _demands.append({}) # Depots have no demand
         _service_times.append(0)
-        _time_windows.append([(0, 1440)]) # Depot open all day
+        _time_windows.append([(opens_at, closes_at)])
         data['starts'].append(start_idx)
         _order_metadata[start_idx] = {"type": "depot_start", "vehicle_id": vid}
         
         # Add END node
         end_idx = len(_locations)
         _locations.append((end_lat, end_lon))
-        _demands.append(0)
+        _demands.append({})
         _service_times.append(0)
-        _time_windows.append([(0, 1440)]) 
+        _time_windows.append([(opens_at, closes_at)])
         data['ends'].append(end_idx)
+        if is_open:
+            data['open_ends'].append(end_idx)
         _order_metadata[end_idx] = {"type": "depot_end", "vehicle_id": vid}
 
 
+    # Capacity dimensions in use, weight first. A vehicle without a capacity
+    # for a dimension is not limited in it (see solver.Unlimited in Go).
+    dimensions = ['weight'] + sorted({name for q in _demands + data['vehicle_capacities'] for name in q} - {'weight'})
+    data['dimensions'] = dimensions
+
     data['num_vehicles'] = len(vehicles)
     data['locations'] = _locations
     data['time_windows'] = _time_windows
@@ -176,6 +256,15 @@ def create_data_model(cursor):
     data['service_times'] = _service_times
     data['_ids'] = _ids 
     data['_order_metadata'] = _order_metadata
+    data['pickups'] = _pickups
+
+    # What leaving out an order costs, by priority (see drop_penalties)
+    cursor.execute("SELECT priority, penalty FROM drop_penalties")
+    data['drop_penalties'] = dict(DEFAULT_DROP_PENALTIES, **dict(cursor.fetchall()))
+    # Pickup-and-delivery pairs with both halves in the problem, as (pickup node, delivery node)
+    node_of = {oid: i for i, oid in enumerate(_ids)}
+    data['pairs'] = [(node_of[oid], node_of[other]) for oid, other in _pair_of.items()
+                     if node_of[oid] in _pickups and other in node_of]
     
     # 3. Compute clusters (Optional - strictly for orders)
     # We only cluster the order nodes (0 to len(orders)-1)
@@ -228,7 +317,14 @@ def compute_time_matrix(locations, speed=AVERAGE_SPEED_M_PER_MIN):
     return time_matrix
 
 
-def optimize():
+FIND_DRAFT_SQL = "SELECT id, version FROM routes WHERE route_date = %s AND status = 'draft' AND depot_id IS NOT DISTINCT FROM %s LIMIT 1"
+
+
+def optimize(depot_id=None, plan_date=None):
+    """
+    Runs one optimization and upserts the draft route of plan_date, today by default (the depot's own draft when depot_id is given).
+    Returns (route_id, objective_value, unassigned orders with a reason); raises if no solution could be produced.
+    """
     print("Connecting to DB...")
     try:
         conn = psycopg2.connect(host=DB_HOST, port=DB_PORT, dbname=DB_NAME, user=DB_USER, password=DB_PASS)
@@ -236,18 +332,22 @@ def optimize():
         cursor = conn.cursor()
     except Exception as e:
         print(f"Connection failed: {e}")
-        return
+        raise
 
-    data = create_data_model(cursor)
+    plan_date = plan_date or datetime.date.today().isoformat()
+    # The draft is only overwritten if nobody touches it while we solve (see routes.version)
+    cursor.execute(FIND_DRAFT_SQL, (plan_date, depot_id))
+    seen_draft = cursor.fetchone()
+    data = create_data_model(cursor, depot_id, plan_date)
     
     if data is None: # Skipped
         conn.close()
-        return
+        raise RuntimeError("nothing to optimize")
 
     if data['num_vehicles'] == 0:
         print("No vehicles found.")
         conn.close()
-        return
+        raise RuntimeError("no vehicles found")
     if len(data['locations']) <= data['num_vehicles'] * 2: 
         # Only vehicle nodes, no orders?
         # Actually logic checked 'orders' length earlier technically but let's be safe
@@ -269,6 +369,11 @@ def optimize():
     # 1. Distance Callback (usando Haversine real)
     distance_matrix = compute_haversine_distance_matrix(data['locations'])
     time_matrix = compute_time_matrix(data['locations'])
+    # Open routes: driving to the end node is free
+    for end_node in data['open_ends']:
+        for from_node in distance_matrix:
+            distance_matrix[from_node][end_node] = 0
+            time_matrix[from_node][end_node] = 0
     
     def distance_callback(from_index, to_index):
         from_node = manager.IndexToNode(from_index)
@@ -278,20 +383,24 @@ def optimize():
     transit_callback_index = routing.RegisterTransitCallback(distance_callback)
     routing.SetArcCostEvaluatorOfAllVehicles(transit_callback_index)
 
-    # 2. Demand Callback
-    def demand_callback(from_index):
-        from_node = manager.IndexToNode(from_index)
-        return data['demands'][from_node]
-
-    demand_callback_index = routing.RegisterUnaryTransitCallback(demand_callback)
-    
-    # Add Capacity constraint.
-    routing.AddDimensionWithVehicleCapacity(
-        demand_callback_index,
-        0,  # null capacity slack
-        data['vehicle_capacities'],  # vehicle maximum capacities
-        True,  # start cumul to zero
-        'Capacity')
+    # 2. Demand Callbacks, one capacity dimension each ("Capacity" is weight).
+    # The cumul is the load on board: pickups add to it, deliveries take from
+    # it, and the start cumul (what leaves the depot) is left for the solver
+    def make_demand_callback(name):
+        def demand_callback(from_index):
+            from_node = manager.IndexToNode(from_index)
+            q = data['demands'][from_node].get(name, 0)
+            return q if from_node in data['pickups'] else -q
+        return demand_callback
+
+    for name in data['dimensions']:
+        demand_callback_index = routing.RegisterUnaryTransitCallback(make_demand_callback(name))
+        routing.AddDimensionWithVehicleCapacity(
+            demand_callback_index,
+            0,  # null capacity slack
+            [caps.get(name, UNLIMITED_CAPACITY) for caps in data['vehicle_capacities']],
+            False,  # start cumul is the depot load
+            'Capacity' if name == 'weight' else f'Capacity_{name}')
 
     # 3. Time Windows Constraint
     def time_callback(from_index, to_index):
@@ -370,7 +479,7 @@ def optimize():
 
     # 4. Infeasibility Handling: Allow dropping nodes
     # "Sempre Permita 'Dropar' Nós (Penalty)" from best-practice.md
-    penalty = 1_000_000  # High penalty to ensure dropped only if necessary
+    # The penalty depends on the order's priority (mandatory costs the most)
     
     # Iterate over ORDERS only. 
     # Current structure: _locations order is [Order1...OrderN, V1Start...VnEnd]
@@ -380,7 +489,23 @@ def optimize():
          node_index = i # In new structure, 0..N-1 are orders
          # Safety check
          if node_index not in data['starts'] and node_index not in data['ends']:
-             routing.AddDisjunction([manager.NodeToIndex(node_index)], penalty)
+             priority = data['_order_metadata'][node_index]['priority']
+             routing.AddDisjunction([manager.NodeToIndex(node_index)], data['drop_penalties'].get(priority, DROP_PENALTY))
+
+    # An order may only ride on vehicles of its depot with every skill it requires,
+    # like Problem.CanServe in Go (it can still be dropped)
+    for i in range(len(data['_ids'])):
+        allowed = [v for v in range(data['num_vehicles']) if can_serve(data, v, i)]
+        if len(allowed) < data['num_vehicles']:
+            routing.SetAllowedVehiclesForIndex(allowed, manager.NodeToIndex(i))
+
+    # Pickup-and-delivery pairs: same vehicle, pickup first, dropped together
+    for pickup, delivery in data['pairs']:
+        pickup_index = manager.NodeToIndex(pickup)
+        delivery_index = manager.NodeToIndex(delivery)
+        routing.AddPickupAndDelivery(pickup_index, delivery_index)
+        routing.solver().Add(routing.VehicleVar(pickup_index) == routing.VehicleVar(delivery_index))
+        routing.solver().Add(time_dimension.CumulVar(pickup_index) <= time_dimension.CumulVar(delivery_index))
 
     # Setting first solution heuristic.
     search_parameters = pywrapcp.DefaultRoutingSearchParameters()
@@ -405,7 +530,8 @@ def optimize():
         total_distance = 0
         
         print(f"Solution found! Objective: {solution.ObjectiveValue()}")
-        solution_output = {"vehicles": []}
+        # Keep in sync with db.SolutionSchemaVersion
+        solution_output = {"schema_version": 1, "vehicles": []}
         
         all_routed_order_ids = []
 
@@ -429,7 +555,8 @@ def optimize():
                 # Identify if it's an order or start
                 if meta.get("type") == "order":
                      step["order_id"] = meta.get("order_id")
-                     step["customer_id"] = meta.get("customer_id")
+                     if meta.get("customer_id"): # NULL for orders without a customer record
+                         step["customer_id"] = meta["customer_id"]
                      step["customer_name"] = meta.get("customer_name")
                      all_routed_order_ids.append(step["order_id"])
                 
@@ -468,42 +595,120 @@ def optimize():
         print(f"\nTotal distance: {total_distance/1000:.1f} km")
         print(f"Total orders routed: {len(all_routed_order_ids)}")
             
+        routed = set(all_routed_order_ids)
+        unassigned = [
+            {"order_id": oid, "reason": unassigned_reason(data, i), "priority": data['_order_metadata'][i]['priority']}
+            for i, oid in enumerate(data['_ids']) if oid not in routed
+        ]
+
         # Save to DB (Upsert)
         json_str = json.dumps(solution_output)
+        unassigned_str = json.dumps(unassigned)
         
-        # Upsert Logic
-        cursor.execute("SELECT id FROM routes WHERE route_date = CURRENT_DATE AND status = 'draft' LIMIT 1")
-        row = cursor.fetchone()
-        
-        route_id = None
-        if row:
-            route_id = row[0]
-            cursor.execute("UPDATE routes SET solution_json = %s, created_at = CURRENT_TIMESTAMP WHERE id = %s", (json_str, route_id))
-            print(f"Updated existing draft route {route_id}.")
-        else:
-            cursor.execute("INSERT INTO routes (solution_json, route_date, status) VALUES (%s, CURRENT_DATE, 'draft') RETURNING id", (json_str,))
-            route_id = cursor.fetchone()[0]
-            print(f"Created new route {route_id}.")
+        # Upsert Logic, conditional on the draft seen before solving like db.SaveDraftSolution.
+        # The draft, its revision and its orders are saved in one transaction, as there.
+        conn.autocommit = False
+        try:
+            route_id = None
+            if seen_draft:
+                route_id, version = seen_draft
+                cursor.execute("UPDATE routes SET solution_json = %s, unassigned = %s, stale = FALSE, created_at = CURRENT_TIMESTAMP WHERE id = %s AND version = %s AND status = 'draft'", (json_str, unassigned_str, route_id, version))
+                if cursor.rowcount == 0:
+                    raise RuntimeError(f"draft route {route_id} was modified while optimizing, run again")
+                print(f"Updated existing draft route {route_id}.")
+            else:
+                cursor.execute(FIND_DRAFT_SQL, (plan_date, depot_id))
+                row = cursor.fetchone()
+                if row:
+                    raise RuntimeError(f"draft route {row[0]} was created while optimizing, run again")
+                cursor.execute("INSERT INTO routes (solution_json, route_date, status, depot_id, unassigned) VALUES (%s, %s, 'draft', %s, %s) RETURNING id", (json_str, plan_date, depot_id, unassigned_str))
+                route_id = cursor.fetchone()[0]
+                print(f"Created new route {route_id}.")
+
+            # Every save is kept as a revision (see route_revisions and db.SolverAuthor)
+            cursor.execute("""
+                INSERT INTO route_revisions (route_id, revision, solution_json, unassigned, source, author)
+                SELECT %(route)s, COALESCE(MAX(revision), 0) + 1, %(solution)s, %(unassigned)s, 'solver', 'optimizer'
+                FROM route_revisions WHERE route_id = %(route)s
+            """, {"route": route_id, "solution": json_str, "unassigned": unassigned_str})
+
+            # Update Order Statuses
+            cursor.execute("UPDATE orders SET status = 'pending', route_id = NULL WHERE route_id = %s", (route_id,))
+
+            if all_routed_order_ids:
+                 cursor.execute("UPDATE orders SET status = 'routed', route_id = %s WHERE id = ANY(%s)", (route_id, all_routed_order_ids))
+
+            conn.commit()
+        except Exception:
+            conn.rollback()
+            raise
+        finally:
+            conn.close()
 
-        # Update Order Statuses
-        cursor.execute("UPDATE orders SET status = 'pending', route_id = NULL WHERE route_id = %s", (route_id,))
-        
-        if all_routed_order_ids:
-             cursor.execute("UPDATE orders SET status = 'routed', route_id = %s WHERE id = ANY(%s)", (route_id, all_routed_order_ids))
-        
         print("Solution saved to database (Upserted).")
+        return route_id, solution.ObjectiveValue(), unassigned
 
-    else:
-        print("No solution found !")
-
+    print("No solution found !")
     conn.close()
+    raise RuntimeError("no solution found")
+
+
+def can_serve(data, vehicle, node):
+    """Whether the vehicle is from the order node's depot, if it has one, and has every skill it requires."""
+    meta = data['_order_metadata'][node]
+    if meta['depot_id'] is not None and meta['depot_id'] != data['vehicle_depots'][vehicle]:
+        return False
+    return set(meta['required_skills']) <= data['vehicle_skills'][vehicle]
+
+
+def unassigned_reason(data, node):
+    """Why an order node was dropped, naming the depot or missing skills like Problem.UnassignedReason in Go."""
+    if not data['num_vehicles']:
+        return "no vehicles available"
+    depot = data['_order_metadata'][node]['depot_id']
+    local = [v for v in range(data['num_vehicles']) if depot is None or data['vehicle_depots'][v] == depot]
+    if not local:
+        return "no vehicle from depot %d" % depot
+    required = set(data['_order_metadata'][node]['required_skills'])
+    if required and not any(required <= data['vehicle_skills'][v] for v in local):
+        offered = set().union(*(data['vehicle_skills'][v] for v in local))
+        missing = sorted(required - offered)
+        if missing:
+            return "no vehicle has skill " + ", ".join(missing)
+        return "no single vehicle has all of " + ", ".join(sorted(required))
+    return "no room left on a suitable vehicle within capacity and time windows"
+
+
+def update_run(run_id, sql, params):
+    """Records progress on an optimization_runs row (see GET /api/optimizations/:id)."""
+    conn = psycopg2.connect(host=DB_HOST, port=DB_PORT, dbname=DB_NAME, user=DB_USER, password=DB_PASS)
+    conn.autocommit = True
+    try:
+        conn.cursor().execute(sql, params + (run_id,))
+    finally:
+        conn.close()
+
+
+def run_optimization(run_id=None, depot_id=None, plan_date=None):
+    """Wraps optimize() with status reporting when a run_id was given."""
+    if run_id is None:
+        return optimize(depot_id, plan_date)
+
+    update_run(run_id, "UPDATE optimization_runs SET status = 'running', started_at = CURRENT_TIMESTAMP WHERE id = %s", ())
+    try:
+        route_id, objective, unassigned = optimize(depot_id, plan_date)
+    except Exception as e:
+        update_run(run_id, "UPDATE optimization_runs SET status = 'failed', error = %s, finished_at = CURRENT_TIMESTAMP WHERE id = %s", (str(e),))
+        raise
+    update_run(run_id, "UPDATE optimization_runs SET status = 'succeeded', route_id = %s, objective_value = %s, unassigned = %s, finished_at = CURRENT_TIMESTAMP WHERE id = %s", (route_id, objective, json.dumps(unassigned)))
 
 def main():
     print("Starting optimization worker...")
     
     # Check if run as one-off script
     if os.environ.get("RUN_ONCE") == "true":
-         optimize()
+         depot_id = os.environ.get("DEPOT_ID")
+         optimize(int(depot_id) if depot_id else None, os.environ.get("PLAN_DATE"))
          return
 
     # Pub/Sub Listener
@@ -534,7 +739,14 @@ def main():
         print(f"Received message: {message.data}")
         message.ack()
         try:
-             optimize()
+            payload = json.loads(message.data)
+        except ValueError:
+            payload = {}
+        # Route lifecycle events share the topic, only reprocess requests are for us
+        if payload.get("action") != "reprocess":
+            return
+        try:
+             run_optimization(payload.get("run_id"), payload.get("depot_id"), payload.get("date"))
         except Exception as e:
             print(f"Error processing message: {e}")
 
@@ -2,7 +2,7 @@
 
 import { useEffect, useState, use } from 'react';
 import { useRouter } from 'next/navigation';
-import { RoutePlanner } from '@/features/routes/components/RoutePlanner';
+import { RoutePlanner, toRouteSolution, fromRouteSolution } from '@/features/routes/components/RoutePlanner';
 import { getOrders } from '@/features/orders/api/orderService';
 import { getVehicles } from '@/features/routes/api/general';
 import { updateRoute, getRoute } from '@/features/routes/api/routes';
@@ -39,7 +39,7 @@ export default function EditRoutePage({ params }: { params: Promise<{ id: string
 
     const handleSave = async (solution: any) => {
         try {
-            await updateRoute(id, { solution_json: solution });
+            await updateRoute(id, { solution_json: toRouteSolution(solution) });
             router.push('/routes');
         } catch (error) {
             console.error(error);
@@ -69,7 +69,7 @@ export default function EditRoutePage({ params }: { params: Promise<{ id: string
 
             <div className="flex-1 min-h-0">
                 <RoutePlanner
-                    initialSolution={data.route.solution_json}
+                    initialSolution={fromRouteSolution(data.route.solution_json)}
                     orders={data.orders}
                     vehicles={data.vehicles}
                     onSave={handleSave}
@@ -2,7 +2,7 @@
 
 import { useEffect, useState } from 'react';
 import { useRouter } from 'next/navigation';
-import { RoutePlanner } from '@/features/routes/components/RoutePlanner';
+import { RoutePlanner, toRouteSolution } from '@/features/routes/components/RoutePlanner';
 import { getOrders } from '@/features/orders/api/orderService';
 import { getVehicles } from '@/features/routes/api/general';
 import { createRoute } from '@/features/routes/api/routes';
@@ -29,7 +29,7 @@ export default function NewRoutePage() {
 
     const handleSave = async (solution: any) => {
         try {
-            await createRoute({ solution_json: solution });
+            await createRoute({ solution_json: toRouteSolution(solution) });
             router.push('/routes');
         } catch (error) {
             console.error(error);
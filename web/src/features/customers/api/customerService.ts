@@ -0,0 +1,60 @@
+import { api } from '@/lib/api';
+import { Order } from '@/features/orders/api/orderService';
+
+export interface Customer {
+    id: number;
+    name: string;
+    lat: number;
+    lon: number;
+    demand: number;
+    time_windows: { start: number; end: number }[];
+    service_duration: number;
+    // Default quantities per dimension for the customer's orders
+    quantities?: Record<string, number>;
+    required_skills?: string[];
+    // Inactive customers keep their history but take no new orders
+    status?: 'active' | 'inactive';
+    updated_at?: string;
+    distance_m?: number; // only in proximity searches
+}
+
+export interface CustomerSearch {
+    q?: string;
+    status?: 'active' | 'inactive';
+    // Proximity search, nearest first; radius defaults to 5 km
+    lat?: number;
+    lon?: number;
+    radius_m?: number;
+}
+
+type CustomerInput = Omit<Customer, 'id' | 'updated_at' | 'distance_m'>;
+
+export const getCustomers = async (search: CustomerSearch = {}) => {
+    const response = await api.get<Customer[]>('/customers', { params: search });
+    return response.data || [];
+};
+
+export const getCustomer = async (id: number) => {
+    const response = await api.get<Customer>(`/customers/${id}`);
+    return response.data;
+};
+
+export const createCustomer = async (customer: CustomerInput) => {
+    const response = await api.post<Customer>('/customers', customer);
+    return response.data;
+};
+
+export const updateCustomer = async (id: number, customer: CustomerInput) => {
+    const response = await api.put<Customer>(`/customers/${id}`, customer);
+    return response.data;
+};
+
+// Fails with 409 when the customer has orders; deactivate it instead
+export const deleteCustomer = async (id: number) => {
+    await api.delete(`/customers/${id}`);
+};
+
+export const getCustomerOrders = async (id: number) => {
+    const response = await api.get<Order[]>(`/customers/${id}/orders`);
+    return response.data || [];
+};
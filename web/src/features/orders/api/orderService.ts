@@ -7,15 +7,26 @@ export interface Order {
     lat: number;
     lon: number;
     demand: number;
+    // Per capacity dimension; demand is the "weight" entry
+    quantities?: Record<string, number>;
+    required_skills?: string[];
+    // A pickup is loaded on site; paired with a delivery it forms a shipment
+    kind?: 'delivery' | 'pickup';
+    pair_order_id?: number | null;
+    priority?: 'mandatory' | 'high' | 'normal' | 'low';
+    delivery_date?: string | null; // YYYY-MM-DD, any day when empty
+    // Set when created from a customer record; read-only
+    customer_synced_at?: string | null;
     time_windows: { start: number; end: number }[];
     service_duration: number;
     created_at?: string;
 }
 
-export const getOrders = async (status?: string, routeId?: number) => {
+export const getOrders = async (status?: string, routeId?: number, date?: string) => {
     const params: any = {};
     if (status) params.status = status;
     if (routeId) params.route_id = routeId;
+    if (date) params.date = date;
 
     const response = await api.get<Order[]>('/orders', {
         params
@@ -23,7 +34,42 @@ export const getOrders = async (status?: string, routeId?: number) => {
     return response.data || [];
 };
 
-export const createOrder = async (order: Omit<Order, 'id'>) => {
-    const response = await api.post('/orders', order);
+// With a customer_id, anything left out is taken from the customer record
+export const createOrder = async (order: Partial<Omit<Order, 'id'>>) => {
+    const response = await api.post<Order>('/orders', order);
+    return response.data;
+};
+
+export const getOrder = async (id: number) => {
+    const response = await api.get<Order>(`/orders/${id}`);
+    return response.data;
+};
+
+// Customer, kind and pair are fixed; quantities, depot and date carry over to the pair partner.
+// Fails with 409 once the order's route is confirmed
+export const updateOrder = async (id: number, order: Omit<Order, 'id'>) => {
+    const response = await api.put<Order>(`/orders/${id}`, order);
+    return response.data;
+};
+
+// Deletes the pair partner too; returns every deleted order ID
+export const deleteOrder = async (id: number) => {
+    const response = await api.delete<{ deleted_order_ids: number[] }>(`/orders/${id}`);
+    return response.data.deleted_order_ids;
+};
+
+// Orders whose customer record changed after they were created from it
+export const getStaleOrders = async () => {
+    const response = await api.get<Order[]>('/orders/stale');
+    return response.data || [];
+};
+
+export interface Shipment {
+    pickup: Order;
+    delivery: Order;
+}
+
+export const createShipment = async (shipment: { pickup: Omit<Order, 'id'>; delivery: Omit<Order, 'id'> }) => {
+    const response = await api.post<Shipment>('/orders/shipments', shipment);
     return response.data;
 };
@@ -1,27 +1,69 @@
 import { api } from '@/lib/api';
 
+// Mirrors db.Solution on the API (schema_version 1)
 export interface RouteStep {
     node_index: number;
-    customer_id: number;
-    order_id?: number; // Added this
+    customer_id?: number;
+    customer_name?: string;
+    order_id?: number;
     min_time: number;
     max_time: number;
+    type?: 'end';
+    wait_time?: number;
+    lateness?: number;
+}
+
+export interface Driver {
+    id: number;
+    name: string;
+    license_category: string;
+    phone: string;
+    status: 'active' | 'inactive' | 'on_leave';
 }
 
 export interface VehicleRoute {
     vehicle_db_id: number;
     route: RouteStep[];
+    total_distance_m?: number;
+    // Only on confirmed routes, read-only
+    driver?: Driver;
 }
 
 export interface RouteSolution {
+    schema_version?: number;
     vehicles: VehicleRoute[];
 }
 
+export interface UnassignedOrder {
+    order_id: number;
+    reason: string;
+    priority?: 'mandatory' | 'high' | 'normal' | 'low';
+}
+
+// draft -> confirmed -> dispatched -> in_progress -> completed; cancelled from any but the last
+export type RouteStatus = 'draft' | 'confirmed' | 'dispatched' | 'in_progress' | 'completed' | 'cancelled';
+
+export const ROUTE_STATUS_LABELS: Record<RouteStatus, string> = {
+    draft: 'Rascunho',
+    confirmed: 'Confirmada',
+    dispatched: 'Despachada',
+    in_progress: 'Em andamento',
+    completed: 'Concluída',
+    cancelled: 'Cancelada',
+};
+
 export interface RouteRecord {
     id: number;
     solution_json: RouteSolution;
     created_at: string;
-    status: string;
+    status: RouteStatus;
+    route_date?: string;
+    // Left out by the last optimization; mandatory ones block confirmation
+    unassigned?: UnassignedOrder[];
+    // An order changed or was deleted since planning; re-optimize or recompute
+    stale?: boolean;
+    // Bumped by every write; send it back as If-Match to avoid overwriting others
+    version?: number;
 }
 
 export const getRoutes = async () => {
@@ -29,17 +71,153 @@ export const getRoutes = async () => {
     return response.data;
 };
 
-export const updateRoute = async (id: number, data: Partial<RouteRecord>) => {
-    const response = await api.put<RouteRecord>(`/routes/${id}`, data);
+export interface Violation {
+    code: string;
+    severity: 'error' | 'warning';
+    vehicle_db_id?: number;
+    order_id?: number;
+    message: string;
+}
+
+// force saves a solution that only has warnings (capacity, time windows)
+// With the version read, fails with 409 (and the current version) if the route changed since
+export const updateRoute = async (id: number, data: Partial<RouteRecord>, force = false, version?: number) => {
+    const response = await api.put<RouteRecord & { warnings?: Violation[] }>(`/routes/${id}`, data, {
+        params: force ? { force: true } : undefined,
+        headers: version !== undefined ? { 'If-Match': `"${version}"` } : undefined,
+    });
     return response.data;
 };
 
+export interface RouteStatusChange {
+    id: number;
+    route_id: number;
+    from_status: RouteStatus;
+    to_status: RouteStatus;
+    changed_by: string;
+    changed_at: string;
+}
+
+const transitionPaths = {
+    confirmed: 'confirm',
+    dispatched: 'dispatch',
+    in_progress: 'start',
+    completed: 'complete',
+    cancelled: 'cancel',
+} as const;
+
+// Moves the route along its lifecycle; illegal transitions fail with 409, as does
+// a version that is no longer current. Cancelling releases the route's orders.
+export const transitionRoute = async (id: number, to: keyof typeof transitionPaths, user?: string, version?: number) => {
+    const headers: Record<string, string> = {};
+    if (user) headers['X-User'] = user;
+    if (version !== undefined) headers['If-Match'] = `"${version}"`;
+    const response = await api.post<RouteRecord>(`/routes/${id}/${transitionPaths[to]}`, undefined, { headers });
+    return response.data;
+};
+
+export const getRouteStatusHistory = async (id: number) => {
+    const response = await api.get<RouteStatusChange[]>(`/routes/${id}/status-history`);
+    return response.data;
+};
+
+export interface RouteRevision {
+    id: number;
+    route_id: number;
+    revision: number;
+    source: 'solver' | 'manual' | 'rollback';
+    author: string;
+    created_at: string;
+    // Only when fetching a single revision
+    solution_json?: RouteSolution;
+    unassigned?: UnassignedOrder[];
+}
+
+export interface StopPosition {
+    vehicle_db_id: number;
+    position: number; // among the vehicle's order stops, from 0
+}
+
+export interface StopChange {
+    order_id: number;
+    from?: StopPosition;
+    to?: StopPosition;
+}
+
+export interface RevisionDiff {
+    from: number;
+    to: number;
+    added: StopChange[];
+    removed: StopChange[];
+    moved: StopChange[];
+}
+
+export const getRouteRevisions = async (id: number) => {
+    const response = await api.get<RouteRevision[]>(`/routes/${id}/revisions`);
+    return response.data;
+};
+
+export const getRouteRevision = async (id: number, revision: number) => {
+    const response = await api.get<RouteRevision>(`/routes/${id}/revisions/${revision}`);
+    return response.data;
+};
+
+export const diffRouteRevisions = async (id: number, from: number, to: number) => {
+    const response = await api.get<RevisionDiff>(`/routes/${id}/revisions/diff`, { params: { from, to } });
+    return response.data;
+};
+
+// Validated like an edit: 422 with violations, warnings can be forced
+export const rollbackRoute = async (id: number, revision: number, force = false) => {
+    const response = await api.post<RouteRecord & { warnings?: Violation[] }>(`/routes/${id}/revisions/${revision}/rollback`, undefined, {
+        params: force ? { force: true } : undefined,
+    });
+    return response.data;
+};
+
+export type OptimizationStatus = 'queued' | 'running' | 'succeeded' | 'failed';
+
+export interface OptimizationRun {
+    id: number;
+    status: OptimizationStatus;
+    requested_route_id: number | null;
+    date: string; // planned day, YYYY-MM-DD
+    route_id: number | null;
+    objective_value: number | null;
+    error: string | null;
+    created_at: string;
+    started_at: string | null;
+    // Orders left out of the route, e.g. "no vehicle has skill refrigerated"
+    unassigned?: UnassignedOrder[];
+    finished_at: string | null;
+}
+
 export const reprocessRoute = async (id: number) => {
-    const response = await api.post(`/routes/${id}/reprocess`);
+    const response = await api.post<{ id: number; status: OptimizationStatus }>(`/routes/${id}/reprocess`);
+    return response.data;
+};
+
+// date (YYYY-MM-DD) plans another day than today
+export const triggerOptimization = async (date?: string) => {
+    const response = await api.post<{ id: number; status: OptimizationStatus }>('/routes/optimize', undefined, {
+        params: date ? { date } : undefined,
+    });
     return response.data;
 };
 
-export const triggerOptimization = async () => {
-    const response = await api.post('/routes/optimize');
+export const getOptimization = async (id: number) => {
+    const response = await api.get<OptimizationRun>(`/optimizations/${id}`);
     return response.data;
 };
+
+// Polls until the run finishes (succeeded or failed) or the timeout expires
+export const waitForOptimization = async (id: number, intervalMs = 2000, timeoutMs = 120000) => {
+    const deadline = Date.now() + timeoutMs;
+    while (true) {
+        const run = await getOptimization(id);
+        if (run.status === 'succeeded' || run.status === 'failed' || Date.now() > deadline) {
+            return run;
+        }
+        await new Promise(resolve => setTimeout(resolve, intervalMs));
+    }
+};
@@ -1,6 +1,8 @@
 import { useState, useEffect } from 'react';
 import dynamic from 'next/dynamic';
-import { RouteRecord, updateRoute, reprocessRoute, triggerOptimization } from '../api/routeService';
+import { RouteRecord, Violation, ROUTE_STATUS_LABELS, updateRoute, transitionRoute, reprocessRoute, triggerOptimization, waitForOptimization } from '../api/routeService';
+import { isAxiosError } from 'axios';
+import { currentUser } from '@/lib/api';
 import { VehicleRouteCard } from './VehicleRouteCard';
 import { Card, CardContent } from "@/components/ui/card"
 import { Button } from '@/components/ui/button';
@@ -55,10 +57,13 @@ export function RouteDashboard({ route, isLoading, onRefresh, isEditing, onEditi
 
     const handleCreateRoute = async () => {
         try {
-            await triggerOptimization();
+            const { id } = await triggerOptimization();
             toast.success("Otimização iniciada para pedidos pendentes!");
-            // Give it a moment to start
-            setTimeout(onRefresh, 1000);
+            const run = await waitForOptimization(id);
+            if (run.status === 'failed') {
+                toast.error(`Otimização falhou: ${run.error}`);
+            }
+            onRefresh();
         } catch (error) {
             console.error(error);
             toast.error("Erro ao iniciar otimização.");
@@ -130,11 +135,28 @@ export function RouteDashboard({ route, isLoading, onRefresh, isEditing, onEditi
         };
 
         try {
-            await updateRoute(route.id, { solution_json: dbSolution as any });
+            await updateRoute(route.id, { solution_json: dbSolution as any }, false, route.version);
             onEditingChange(false);
             onRefresh();
         } catch (error) {
             console.error(error);
+            if (isAxiosError(error) && error.response?.status === 422) {
+                const violations: Violation[] = error.response.data.violations || [];
+                const hasErrors = violations.some(v => v.severity === 'error');
+                const summary = violations.map(v => `• ${v.message}`).join('\n');
+                if (!hasErrors && window.confirm(`A rota tem alertas:\n${summary}\n\nSalvar mesmo assim?`)) {
+                    await updateRoute(route.id, { solution_json: dbSolution as any }, true, route.version);
+                    onEditingChange(false);
+                    onRefresh();
+                    return;
+                }
+                toast.error(`Rota inválida: ${violations.map(v => v.message).join('; ')}`);
+                throw error;
+            }
+            if (isAxiosError(error) && error.response?.status === 409) {
+                toast.error("A rota foi alterada por outra pessoa. Recarregue e tente novamente.");
+                throw error;
+            }
             toast.error("Erro ao salvar a rota.");
             throw error;
         }
@@ -143,11 +165,17 @@ export function RouteDashboard({ route, isLoading, onRefresh, isEditing, onEditi
     const handleConfirmRoute = async () => {
         setIsConfirming(true);
         try {
-            await updateRoute(route.id, { status: 'confirmed' });
+            await transitionRoute(route.id, 'confirmed', currentUser(), route.version);
             toast.success("Rota confirmada!");
             onRefresh();
         } catch (error) {
             console.error(error);
+            if (isAxiosError(error) && error.response?.status === 409) {
+                toast.error(error.response.data?.version !== undefined
+                    ? "A rota foi alterada por outra pessoa. Recarregue e tente novamente."
+                    : "Não foi possível confirmar a rota.");
+                return;
+            }
             toast.error("Erro ao confirmar rota.");
         } finally {
             setIsConfirming(false);
@@ -157,8 +185,13 @@ export function RouteDashboard({ route, isLoading, onRefresh, isEditing, onEditi
     const handleReprocess = async () => {
         setIsReprocessing(true);
         try {
-            await reprocessRoute(route.id);
-            toast.success("Reprocessamento solicitado! Aguarde e atualize.");
+            const { id } = await reprocessRoute(route.id);
+            toast.success("Reprocessamento solicitado!");
+            const run = await waitForOptimization(id);
+            if (run.status === 'failed') {
+                toast.error(`Reprocessamento falhou: ${run.error}`);
+            }
+            onRefresh();
         } catch (error) {
             console.error(error);
             toast.error("Erro ao solicitar reprocessamento.");
@@ -207,7 +240,7 @@ export function RouteDashboard({ route, isLoading, onRefresh, isEditing, onEditi
         }
     });
 
-    const isConfirmed = route.status === 'confirmed';
+    const isConfirmed = route.status !== 'draft';
 
     return (
         <div className="p-6 space-y-8 h-full overflow-y-auto bg-muted/10">
@@ -216,7 +249,7 @@ export function RouteDashboard({ route, isLoading, onRefresh, isEditing, onEditi
                 <div>
                     <div className="flex items-center gap-3">
                         <h2 className="text-2xl font-bold tracking-tight">Detalhes da Otimização #{route.id}</h2>
-                        {isConfirmed && <span className="px-2 py-1 bg-green-100 text-green-700 text-xs rounded-full font-medium flex items-center gap-1 border border-green-200"><CheckCircle className="h-3 w-3" /> Confirmada</span>}
+                        {isConfirmed && <span className="px-2 py-1 bg-green-100 text-green-700 text-xs rounded-full font-medium flex items-center gap-1 border border-green-200"><CheckCircle className="h-3 w-3" /> {ROUTE_STATUS_LABELS[route.status]}</span>}
                         {!isConfirmed && <span className="px-2 py-1 bg-yellow-100 text-yellow-800 text-xs rounded-full font-medium border border-yellow-200">Rascunho</span>}
                     </div>
                     <p className="text-muted-foreground mt-1 text-sm flex items-center gap-2">
@@ -47,6 +47,27 @@ interface Solution {
     vehicles: VehicleRoute[];
 }
 
+// The API stores vehicles under vehicle_db_id and rejects unknown fields,
+// so planner solutions have to be converted before saving.
+export function toRouteSolution(solution: Solution) {
+    return {
+        vehicles: solution.vehicles.map(v => ({
+            vehicle_db_id: v.vehicle_id,
+            route: v.route.map(r => ({ order_id: r.order_id, node_index: 0, min_time: 0, max_time: 0 })),
+        })),
+    };
+}
+
+export function fromRouteSolution(solution?: { vehicles: { vehicle_db_id: number; route: { order_id?: number }[] }[] }): Solution | undefined {
+    if (!solution) return undefined;
+    return {
+        vehicles: solution.vehicles.map(v => ({
+            vehicle_id: v.vehicle_db_id,
+            route: v.route.filter(r => r.order_id).map(r => ({ order_id: r.order_id as number })),
+        })),
+    };
+}
+
 interface RoutePlannerProps {
     initialSolution?: Solution;
     orders: Order[]; // was customers
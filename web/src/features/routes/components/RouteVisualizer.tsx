@@ -1,7 +1,7 @@
 "use client"
 
 import { useEffect, useState, useMemo } from 'react';
-import { RouteRecord, getRoutes } from '../api/routeService';
+import { RouteRecord, ROUTE_STATUS_LABELS, getRoutes } from '../api/routeService';
 import { getOrders, Order } from '@/features/orders/api/orderService';
 import { Card, CardHeader, CardTitle, CardContent } from "@/components/ui/card"
 import dynamic from 'next/dynamic';
@@ -73,11 +73,11 @@ export function RouteVisualizer({ keyTrigger }: { keyTrigger: number }) {
                         Visualização da última otimização ({new Date(latestRoute.created_at).toLocaleTimeString()})
                     </p>
                 </div>
-                <span className={`px-2 py-1 rounded-full text-xs font-medium border ${latestRoute.status === 'confirmed'
+                <span className={`px-2 py-1 rounded-full text-xs font-medium border ${latestRoute.status !== 'draft'
                         ? 'bg-green-100 text-green-700 border-green-200'
                         : 'bg-yellow-100 text-yellow-700 border-yellow-200'
                     }`}>
-                    {latestRoute.status === 'confirmed' ? 'Confirmada' : 'Rascunho'}
+                    {ROUTE_STATUS_LABELS[latestRoute.status]}
                 </span>
             </div>
 
@@ -6,10 +6,22 @@ export interface Vehicle {
     capacity: number;
     start_lat: number;
     start_lon: number;
+    end_lat?: number | null;
+    end_lon?: number | null;
+    route_mode?: 'return' | 'open';
+    depot_id?: number | null;
+    required_license?: string;
+    // Per capacity dimension; capacity is the "weight" entry
+    capacities?: Record<string, number>;
+    skills?: string[];
+    // Retired vehicles are hidden from listings and optimization
+    active?: boolean;
 }
 
-export const getVehicles = async () => {
-    const response = await api.get<Vehicle[]>('/vehicles');
+export const getVehicles = async (includeInactive = false) => {
+    const response = await api.get<Vehicle[]>('/vehicles', {
+        params: includeInactive ? { include_inactive: true } : {}
+    });
     return response.data || [];
 };
 
@@ -22,3 +34,10 @@ export const updateVehicle = async (id: number, vehicle: Omit<Vehicle, 'id'>) =>
     const response = await api.put(`/vehicles/${id}`, vehicle);
     return response.data;
 };
+
+// Fails with 409 while a confirmed route uses the vehicle; deactivate it instead.
+// Returns the draft routes left stale.
+export const deleteVehicle = async (id: number) => {
+    const response = await api.delete<{ affected_draft_route_ids: number[] }>(`/vehicles/${id}`);
+    return response.data.affected_draft_route_ids;
+};
@@ -42,7 +42,16 @@ export function VehicleForm({ onSuccess, vehicle }: { onSuccess: () => void, veh
                     name: data.name,
                     capacity: data.capacity,
                     start_lat: data.start_lat,
-                    start_lon: data.start_lon
+                    start_lon: data.start_lon,
+                    end_lat: vehicle.end_lat,
+                    end_lon: vehicle.end_lon,
+                    route_mode: vehicle.route_mode,
+                    depot_id: vehicle.depot_id,
+                    required_license: vehicle.required_license,
+                    // Weight in capacities overrides capacity, keep them in step
+                    capacities: { ...vehicle.capacities, weight: data.capacity },
+                    skills: vehicle.skills,
+                    active: vehicle.active ?? true
                 });
                 toast.success("Veículo atualizado com sucesso!");
             } else {
@@ -50,7 +59,8 @@ export function VehicleForm({ onSuccess, vehicle }: { onSuccess: () => void, veh
                     name: data.name,
                     capacity: data.capacity,
                     start_lat: data.start_lat,
-                    start_lon: data.start_lon
+                    start_lon: data.start_lon,
+                    active: true
                 });
                 toast.success("Veículo cadastrado com sucesso!");
             }
@@ -6,3 +6,26 @@ export const api = axios.create({
         'Content-Type': 'application/json',
     },
 });
+
+const USER_KEY = 'routego.user';
+
+// Name recorded as the author of route changes, asked once per browser
+export const currentUser = (): string | undefined => {
+    if (typeof window === 'undefined') return undefined;
+    let user = window.localStorage.getItem(USER_KEY);
+    if (!user) {
+        user = window.prompt('Seu nome (registrado nas alterações de rotas):')?.trim() || null;
+        if (user) window.localStorage.setItem(USER_KEY, user);
+    }
+    return user ?? undefined;
+};
+
+// Writes carry the user so status changes and revisions record who made them
+api.interceptors.request.use((config) => {
+    const method = config.method?.toLowerCase();
+    if (method && method !== 'get' && !config.headers.has('X-User')) {
+        const user = currentUser();
+        if (user) config.headers.set('X-User', user);
+    }
+    return config;
+});